@@ -1,12 +1,19 @@
 package v1alpha1
 
 import (
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
 	// SourceConfigFileName default name of the source repository configuration
 	SourceConfigFileName = "source-config.yaml"
+
+	// JenkinsServerFormatHelm the Jenkins server is configured via the helm chart values
+	JenkinsServerFormatHelm = "helm"
+
+	// JenkinsServerFormatOperator the Jenkins server is configured via a Jenkins Operator custom resource
+	JenkinsServerFormatOperator = "operator"
 )
 
 // +genclient
@@ -43,6 +50,27 @@ type SourceConfigSpec struct {
 
 	// Scheduler the default scheduler for any group/repository which does not specify one
 	Scheduler string `json:"scheduler,omitempty"`
+
+	// JenkinsServers the configuration of the Jenkins servers
+	JenkinsServers []JenkinsServerConfig `json:"jenkinsServers,omitempty"`
+
+	// Defaults the default values for any group/repository which does not specify them
+	Defaults *RepositoryDefaults `json:"defaults,omitempty"`
+}
+
+// RepositoryDefaults the default values applied to all groups and repositories before the group level defaults
+type RepositoryDefaults struct {
+	// Provider the default git provider server URL
+	Provider string `json:"provider,omitempty"`
+
+	// ProviderKind the default git provider kind
+	ProviderKind string `json:"providerKind,omitempty"`
+
+	// ProviderName the default git provider name
+	ProviderName string `json:"providerName,omitempty"`
+
+	// Jenkins the default jenkins configuration
+	Jenkins *JenkinsConfig `json:"jenkins,omitempty"`
 }
 
 // SourceConfigSpec defines the desired state of SourceConfig.
@@ -67,6 +95,12 @@ type RepositoryGroup struct {
 
 	// Jenkins the jenkins configuration if using Jenkins
 	Jenkins *JenkinsConfig `json:"jenkins,omitempty"`
+
+	// Language the main programming language of the repositories such as 'go' or 'java'
+	Language string `json:"language,omitempty"`
+
+	// DependencyManager the dependency manager of the repositories such as 'gomod' or 'maven'
+	DependencyManager string `json:"dependencyManager,omitempty"`
 }
 
 // Repository the name of the repository to import and the optional scheduler
@@ -91,6 +125,30 @@ type Repository struct {
 
 	// SSHCloneURL the SSH based clone URL
 	SSHCloneURL string `json:"sshCloneURL,omitempty"`
+
+	// Drone the optional Drone CI configuration
+	Drone *DroneConfig `json:"drone,omitempty"`
+
+	// Buildkite the optional Buildkite configuration
+	Buildkite *BuildkiteConfig `json:"buildkite,omitempty"`
+
+	// Semaphore the optional Semaphore CI configuration
+	Semaphore *SemaphoreConfig `json:"semaphore,omitempty"`
+
+	// Harness the optional Harness CI configuration
+	Harness *HarnessConfig `json:"harness,omitempty"`
+
+	// TeamCity the optional TeamCity configuration
+	TeamCity *TeamCityConfig `json:"teamcity,omitempty"`
+
+	// Infra the optional infrastructure the repository requires which is provisioned via Crossplane claims
+	Infra *InfraConfig `json:"infra,omitempty"`
+
+	// Spinnaker the optional Spinnaker pipeline configuration
+	Spinnaker *SpinnakerConfig `json:"spinnaker,omitempty"`
+
+	// Keptn the optional Keptn quality gate configuration used to generate the SLO and SLI files
+	Keptn *KeptnConfig `json:"keptn,omitempty"`
 }
 
 // JenkinsConfig the Jenkins configuration for a group or repository if applicable
@@ -100,4 +158,196 @@ type JenkinsConfig struct {
 
 	// Server the name of the Jenkins Server to use
 	Server string `json:"server,omitempty"`
+
+	// SharedLibraries the Jenkins shared libraries to configure via the global node properties and existing secrets of the Jenkins Server
+	SharedLibraries []SharedLibraryConfig `json:"sharedLibraries,omitempty"`
+
+	// GlobalLibraries the pipeline libraries to register in the globalLibraries helm values of the Jenkins Server
+	GlobalLibraries []SharedLibraryConfig `json:"globalLibraries,omitempty"`
+
+	// SlackNotification the Slack notifications to send when the jobs complete
+	SlackNotification *SlackConfig `json:"slackNotification,omitempty"`
+
+	// SonarQube the SonarQube analysis configuration of the jobs
+	SonarQube *SonarConfig `json:"sonarQube,omitempty"`
+
+	// NexusIQ the Nexus IQ policy evaluation configuration of the jobs
+	NexusIQ *NexusConfig `json:"nexusIQ,omitempty"`
+
+	// KEDA the KEDA ScaledJob configuration used to auto scale the build agents of the jobs
+	KEDA *KEDAConfig `json:"keda,omitempty"`
+
+	// BuildTimeout the optional maximum duration of a build after which it is aborted
+	BuildTimeout *metav1.Duration `json:"buildTimeout,omitempty"`
+}
+
+// SonarConfig the configuration of the SonarQube analysis of a Jenkins job
+type SonarConfig struct {
+	// ProjectKey the SonarQube project key. If not specified it is derived from the owner and repository name
+	ProjectKey string `json:"projectKey,omitempty"`
+
+	// ServerURL the URL of the SonarQube server
+	ServerURL string `json:"serverUrl,omitempty"`
+
+	// QualityGate the name of the quality gate the project must pass
+	QualityGate string `json:"qualityGate,omitempty"`
+}
+
+// NexusConfig the configuration of the Nexus IQ policy evaluation of a Jenkins job
+type NexusConfig struct {
+	// ApplicationID the Nexus IQ application ID. If not specified it is derived from the owner and repository name
+	ApplicationID string `json:"applicationId,omitempty"`
+
+	// Stage the Nexus IQ stage of the policy evaluation such as 'build' or 'release'. Defaults to 'build'
+	Stage string `json:"stage,omitempty"`
+}
+
+// KEDAConfig the configuration of the KEDA ScaledJob which auto scales the Jenkins build agents of a job based on the queue depth
+type KEDAConfig struct {
+	// MinReplicas the minimum number of build agent pods. Defaults to 0
+	MinReplicas int `json:"minReplicas,omitempty"`
+
+	// MaxReplicas the maximum number of build agent pods. Defaults to 10
+	MaxReplicas int `json:"maxReplicas,omitempty"`
+
+	// QueueLabel the label of the Jenkins build queue the agents serve. If not specified the repository name is used
+	QueueLabel string `json:"queueLabel,omitempty"`
+}
+
+// SlackConfig the configuration of the Slack notifications of a Jenkins job
+type SlackConfig struct {
+	// Channel the Slack channel to notify
+	Channel string `json:"channel" validate:"nonzero"`
+
+	// OnSuccess if enabled a notification is sent when the job succeeds
+	OnSuccess bool `json:"onSuccess,omitempty"`
+
+	// OnFailure if enabled a notification is sent when the job fails
+	OnFailure bool `json:"onFailure,omitempty"`
+
+	// OnUnstable if enabled a notification is sent when the job is unstable
+	OnUnstable bool `json:"onUnstable,omitempty"`
+}
+
+// SharedLibraryConfig the configuration of a Jenkins shared library
+type SharedLibraryConfig struct {
+	// Name the name of the shared library
+	Name string `json:"name" validate:"nonzero"`
+
+	// URL the git URL of the shared library
+	URL string `json:"url" validate:"nonzero"`
+
+	// DefaultVersion the default git reference of the shared library to use
+	DefaultVersion string `json:"defaultVersion,omitempty"`
+
+	// Implicit if enabled the shared library is loaded by all pipelines without an explicit @Library annotation
+	Implicit bool `json:"implicit,omitempty"`
+
+	// CredentialID the ID of the Jenkins credentials used to clone the shared library
+	CredentialID string `json:"credentialId,omitempty"`
+}
+
+// DroneConfig the Drone CI configuration for a repository
+type DroneConfig struct {
+	// Runner the kind of Drone runner used to execute the pipeline such as 'docker' or 'kubernetes'
+	Runner string `json:"runner,omitempty"`
+}
+
+// BuildkiteConfig the Buildkite configuration for a repository
+type BuildkiteConfig struct {
+	// Queue the agent queue used to run the pipeline steps
+	Queue string `json:"queue,omitempty"`
+}
+
+// SemaphoreConfig the Semaphore CI configuration for a repository
+type SemaphoreConfig struct {
+	// Organization the Semaphore organization. If not specified the owner of the repository is used
+	Organization string `json:"organization,omitempty"`
+}
+
+// HarnessConfig the Harness CI configuration for a repository
+type HarnessConfig struct {
+	// Project the Harness project identifier. If not specified the owner of the repository is used
+	Project string `json:"project,omitempty"`
+
+	// Organization the Harness organization identifier
+	Organization string `json:"organization,omitempty"`
+}
+
+// TeamCityConfig the TeamCity configuration for a repository
+type TeamCityConfig struct {
+	// ProjectID the TeamCity project ID. If not specified the owner of the repository is used
+	ProjectID string `json:"projectId,omitempty"`
+
+	// VCSRootID the TeamCity VCS root ID. If not specified it is derived from the project ID and repository name
+	VCSRootID string `json:"vcsRootId,omitempty"`
+}
+
+// InfraConfig the infrastructure required by a repository
+type InfraConfig struct {
+	// Claims the Crossplane claims of the infrastructure resources such as databases or message queues
+	Claims []ClaimConfig `json:"claims,omitempty"`
+}
+
+// ClaimConfig the configuration of a Crossplane claim
+type ClaimConfig struct {
+	// Kind the kind of the claim such as 'PostgreSQLInstance'. The template used is the lower case kind with a .yaml.gotmpl extension
+	Kind string `json:"kind" validate:"nonzero"`
+
+	// Name the name of the claim. If not specified it is derived from the repository name and kind
+	Name string `json:"name,omitempty"`
+
+	// Parameters the parameters of the claim
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// SpinnakerConfig the Spinnaker pipeline configuration for a repository
+type SpinnakerConfig struct {
+	// Application the Spinnaker application. If not specified the repository name is used
+	Application string `json:"application,omitempty"`
+
+	// Pipeline the name of the Spinnaker pipeline. Defaults to 'deploy'
+	Pipeline string `json:"pipeline,omitempty"`
+}
+
+// KeptnConfig the Keptn quality gate configuration for a repository
+type KeptnConfig struct {
+	// Project the Keptn project. If not specified the owner of the group is used
+	Project string `json:"project,omitempty"`
+
+	// Stage the Keptn stage the quality gate is evaluated in. Defaults to 'production'
+	Stage string `json:"stage,omitempty"`
+
+	// Service the Keptn service. If not specified the repository name is used
+	Service string `json:"service,omitempty"`
+}
+
+// JenkinsServerConfig the configuration of a Jenkins server
+type JenkinsServerConfig struct {
+	// Server the name of the Jenkins server
+	Server string `json:"server" validate:"nonzero"`
+
+	// Credentials the credentials required by the jobs on the Jenkins server
+	Credentials []CredentialConfig `json:"credentials,omitempty"`
+
+	// Format how the Jenkins server is configured. Either 'helm' (the default) to generate helm values or 'operator' to generate a Jenkins Operator custom resource
+	Format string `json:"format,omitempty"`
+
+	// Image the container image of the Jenkins master when using the 'operator' format
+	Image string `json:"image,omitempty"`
+
+	// Roles the RBAC roles bound to the Jenkins master when using the 'operator' format
+	Roles []rbacv1.RoleRef `json:"roles,omitempty"`
+}
+
+// CredentialConfig a credential required by the jobs on a Jenkins server
+type CredentialConfig struct {
+	// ID the ID of the credential used to reference it from jobs
+	ID string `json:"id" validate:"nonzero"`
+
+	// Kind the kind of credential such as 'usernamePassword', 'string' or 'basicSSHUserPrivateKey'. Defaults to 'usernamePassword'
+	Kind string `json:"kind,omitempty"`
+
+	// Description the optional description of the credential
+	Description string `json:"description,omitempty"`
 }
@@ -0,0 +1,131 @@
+package jobs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const s3Scheme = "s3://"
+
+// S3GetObjectAPI the S3 API used to fetch templates
+type S3GetObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// SecretsManagerGetSecretValueAPI the Secrets Manager API used to load the source config
+type SecretsManagerGetSecretValueAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// awsConfig loads the AWS configuration using the standard credential chain
+func (o *Options) awsConfig() (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if o.AWSRegion != "" {
+		opts = append(opts, config.WithRegion(o.AWSRegion))
+	}
+	if o.AWSProfile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(o.AWSProfile))
+	}
+	cfg, err := config.LoadDefaultConfig(context.TODO(), opts...)
+	if err != nil {
+		return cfg, errors.Wrapf(err, "failed to load AWS configuration")
+	}
+	return cfg, nil
+}
+
+// loadSourceConfigFromSecret loads the source config YAML from the AWS Secrets Manager secret
+func (o *Options) loadSourceConfigFromSecret() error {
+	if o.SecretsManagerClient == nil {
+		cfg, err := o.awsConfig()
+		if err != nil {
+			return err
+		}
+		o.SecretsManagerClient = secretsmanager.NewFromConfig(cfg)
+	}
+
+	output, err := o.SecretsManagerClient.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(o.AWSSecretARN),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get secret value")
+	}
+	data := output.SecretBinary
+	if output.SecretString != nil {
+		data = []byte(aws.ToString(output.SecretString))
+	}
+	err = yaml.Unmarshal(data, &o.SourceConfig)
+	if err != nil {
+		return errors.Wrapf(err, "failed to unmarshal source config YAML")
+	}
+	return nil
+}
+
+func isS3URL(path string) bool {
+	return strings.HasPrefix(path, s3Scheme)
+}
+
+// loadS3Template loads the template from the given s3://bucket/key URL caching the results
+func (o *Options) loadS3Template(path string) (string, error) {
+	if text, ok := o.s3Templates[path]; ok {
+		return text, nil
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse S3 URL %s", path)
+	}
+	bucket := u.Host
+	if bucket == "" {
+		bucket = o.S3Bucket
+	}
+	if bucket == "" {
+		return "", errors.Errorf("no bucket in S3 URL %s and no --s3-bucket specified", path)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+
+	if o.S3Client == nil {
+		cfg, err := o.awsConfig()
+		if err != nil {
+			return "", err
+		}
+		o.S3Client = s3.NewFromConfig(cfg)
+	}
+
+	start := time.Now()
+	output, err := o.S3Client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get object %s from bucket %s", key, bucket)
+	}
+	defer output.Body.Close()
+
+	err = o.checkTemplateSize(path, output.ContentLength)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read object %s from bucket %s", key, bucket)
+	}
+	log.Logger().Debugf("fetched template %s from S3 in %s", path, time.Since(start).String())
+
+	if o.s3Templates == nil {
+		o.s3Templates = map[string]string{}
+	}
+	text := string(data)
+	o.s3Templates[path] = text
+	return text, nil
+}
@@ -0,0 +1,82 @@
+package jobs_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeS3 struct {
+	requests []string
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.requests = append(f.requests, aws.ToString(input.Bucket)+"/"+aws.ToString(input.Key))
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader("<project>{{ .Repository }}</project>")),
+	}, nil
+}
+
+func TestJenkinsJobsS3Template(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	fake := &fakeS3{}
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ConfigFile = filepath.Join("test_data", "s3", "source-config.yaml")
+	o.S3Client = fake
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	assert.Equal(t, []string{"mybucket/templates/default.xml.gotmpl"}, fake.requests, "S3 requests")
+
+	valuesFile := filepath.Join(tmpDir, "myjenkins", "values.yaml")
+	data, err := ioutil.ReadFile(valuesFile)
+	require.NoError(t, err, "failed to load file %s", valuesFile)
+	assert.Contains(t, string(data), "<project>myapp</project>", "values file %s", valuesFile)
+}
+
+type fakeSecretsManager struct {
+	value string
+}
+
+func (f *fakeSecretsManager) GetSecretValue(ctx context.Context, input *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	return &secretsmanager.GetSecretValueOutput{
+		ARN:          input.SecretId,
+		SecretString: aws.String(f.value),
+	}, nil
+}
+
+func TestJenkinsJobsAWSSecret(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	configFile := filepath.Join("test_data", ".jx", "gitops", "source-config.yaml")
+	data, err := ioutil.ReadFile(configFile)
+	require.NoError(t, err, "failed to load file %s", configFile)
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ConfigFile = filepath.Join(tmpDir, "does-not-exist.yaml")
+	o.AWSSecretARN = "arn:aws:secretsmanager:us-east-1:123456789012:secret:source-config"
+	o.SecretsManagerClient = &fakeSecretsManager{value: string(data)}
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	valuesFile := filepath.Join(tmpDir, "myjenkins", "values.yaml")
+	assert.FileExists(t, valuesFile, "should have generated the values file")
+}
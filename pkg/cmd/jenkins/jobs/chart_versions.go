@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+)
+
+const (
+	// LatestHelmChartVersion the latest known stable version of the Jenkins helm chart
+	LatestHelmChartVersion = "3.3.0"
+
+	// DefaultValuesKey the values key used to configure the Jenkins controller if no helm chart version is specified
+	DefaultValuesKey = "master"
+)
+
+// helmChartValuesKeys maps the minimum version of the Jenkins helm chart to the values key used to configure the Jenkins controller.
+// The entries are ordered by descending version
+var helmChartValuesKeys = []struct {
+	MinVersion string
+	Key        string
+}{
+	{MinVersion: "3.0.0", Key: "controller"},
+	{MinVersion: "0.0.0", Key: "master"},
+}
+
+// ValuesKeyForChartVersion returns the values key used to configure the Jenkins controller in the given version of the Jenkins helm chart
+func ValuesKeyForChartVersion(version string) (string, error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse helm chart version %s", version)
+	}
+	for _, e := range helmChartValuesKeys {
+		if !v.LessThan(semver.MustParse(e.MinVersion)) {
+			return e.Key, nil
+		}
+	}
+	return "", errors.Errorf("unsupported helm chart version %s", version)
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/pkg/errors"
+)
+
+const (
+	// OutputCompressionGzip writes the values files gzip compressed with a .gz extension
+	OutputCompressionGzip = "gzip"
+
+	// gzipExtension the file extension of gzip compressed files
+	gzipExtension = ".gz"
+)
+
+// OutputCompressions the supported values of --output-compression
+var OutputCompressions = []string{OutputCompressionGzip}
+
+// valuesFileName returns the name of the values file taking into account the --output-compression
+func (o *Options) valuesFileName() string {
+	if o.OutputCompression == OutputCompressionGzip {
+		return "values.yaml" + gzipExtension
+	}
+	return "values.yaml"
+}
+
+// readFile reads the file decompressing it if it has a .gz extension
+func readFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load file %s", path)
+	}
+	if !strings.HasSuffix(path, gzipExtension) {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create gzip reader for %s", path)
+	}
+	defer r.Close()
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decompress file %s", path)
+	}
+	return data, nil
+}
+
+// writeFile writes the file compressing it if it has a .gz extension
+func writeFile(path string, data []byte) error {
+	if strings.HasSuffix(path, gzipExtension) {
+		buf := &bytes.Buffer{}
+		w := gzip.NewWriter(buf)
+		_, err := w.Write(data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to compress file %s", path)
+		}
+		err = w.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to compress file %s", path)
+		}
+		data = buf.Bytes()
+	}
+	err := ioutil.WriteFile(path, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jenkins-x/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-gitops/pkg/sourceconfigs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	depsLong = templates.LongDesc(`
+		Outputs a JSON map of each repository URL to the template files it depends on
+
+The partial templates included via the include function are resolved recursively so that when a template file changes all the repositories which need regenerating can be found.
+`)
+
+	depsExample = templates.Examples(`
+		# output the dependencies to the console
+		%s jenkins jobs deps
+
+		# output the dependencies to a file
+		%s jenkins jobs deps --output deps.json
+	`)
+
+	includeRegex = regexp.MustCompile(`include\s+"([^"]+)"`)
+)
+
+// DepsOptions the options for the deps command
+type DepsOptions struct {
+	Options
+	OutputFile string
+	Out        io.Writer
+}
+
+// NewCmdJenkinsJobsDeps creates a command object for the command
+func NewCmdJenkinsJobsDeps() (*cobra.Command, *DepsOptions) {
+	o := &DepsOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "deps",
+		Short:   "Outputs a JSON map of each repository URL to the template files it depends on",
+		Long:    depsLong,
+		Example: fmt.Sprintf(depsExample, rootcmd.BinaryName, rootcmd.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the current working directory")
+	cmd.Flags().StringVarP(&o.ConfigFile, "config", "c", "", "the configuration file to load for the repository configurations. If not specified we look in ./.jx/gitops/source-config.yaml")
+	cmd.Flags().StringVarP(&o.DefaultXmlTemplate, "default-xml-template", "", "", "the default XML template file if none is configured for a repository")
+	cmd.Flags().StringVarP(&o.OutputFile, "output", "o", "", "the file to write the JSON dependencies to. If not specified they are written to the console")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *DepsOptions) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	deps := map[string][]string{}
+	config := &o.SourceConfig
+	for i := range config.Spec.Groups {
+		group := &config.Spec.Groups[i]
+		for j := range group.Repositories {
+			repo := &group.Repositories[j]
+			sourceconfigs.DefaultValues(config, group, repo)
+			jc := repo.Jenkins
+			if jc == nil {
+				continue
+			}
+			xmlTemplate := o.xmlTemplatePath(jc)
+			if xmlTemplate == "" {
+				continue
+			}
+			text, err := o.loadXMLTemplate(xmlTemplate)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load XML template %s", xmlTemplate)
+			}
+			paths := []string{o.relativeTemplatePath(xmlTemplate)}
+			paths, err = o.addPartials(paths, text)
+			if err != nil {
+				return errors.Wrapf(err, "failed to resolve partials of template %s", xmlTemplate)
+			}
+			deps[repo.URL] = paths
+		}
+	}
+
+	data, err := json.MarshalIndent(deps, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dependencies")
+	}
+	if o.OutputFile == "" {
+		if o.Out == nil {
+			o.Out = os.Stdout
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	}
+	err = ioutil.WriteFile(o.OutputFile, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", o.OutputFile)
+	}
+	log.Logger().Infof("created dependencies file %s", info(o.OutputFile))
+	return nil
+}
+
+// addPartials recursively adds the partial templates included by the template text
+func (o *DepsOptions) addPartials(paths []string, text string) ([]string, error) {
+	for _, m := range includeRegex.FindAllStringSubmatch(text, -1) {
+		name := filepath.Clean(m[1])
+		found := false
+		for _, p := range paths {
+			if p == name {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		paths = append(paths, name)
+
+		path := filepath.Join(o.Dir, name)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return paths, errors.Wrapf(err, "failed to load partial template %s", path)
+		}
+		paths, err = o.addPartials(paths, string(data))
+		if err != nil {
+			return paths, err
+		}
+	}
+	return paths, nil
+}
+
+// relativeTemplatePath returns the template path relative to the directory if possible
+func (o *DepsOptions) relativeTemplatePath(path string) string {
+	rel, err := filepath.Rel(o.Dir, path)
+	if err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,29 @@
+package jobs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJenkinsJobsDeps(t *testing.T) {
+	buf := &bytes.Buffer{}
+	_, o := jobs.NewCmdJenkinsJobsDeps()
+	o.Dir = "test_data"
+	o.Out = buf
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the command")
+
+	deps := map[string][]string{}
+	err = json.Unmarshal(buf.Bytes(), &deps)
+	require.NoError(t, err, "failed to parse output %s", buf.String())
+
+	expected := []string{"jenkins/templates/default.xml.gotmpl", "jenkins/templates/partials/triggers.xml.gotmpl", "jenkins/templates/partials/credentials.xml.gotmpl"}
+	assert.Equal(t, expected, deps["https://github.com/myorg/myapp"], "dependencies of myapp")
+	assert.Equal(t, expected, deps["https://github.com/myorg/another"], "dependencies of another")
+}
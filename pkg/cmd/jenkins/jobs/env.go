@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"path/filepath"
+	"reflect"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// writeEnvValues writes the values-<env>.yaml overlay containing only the environment values which differ from the base values
+func (o *Options) writeEnvValues(dir string, data []byte) error {
+	base := map[string]interface{}{}
+	err := yaml.Unmarshal(data, &base)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse the base values")
+	}
+
+	overlay := diffValues(base, o.envValues)
+
+	path := filepath.Join(dir, "values-"+o.Env+".yaml")
+	log.Logger().Infof("creating Jenkins environment values file %s", path)
+	err = yamls.SaveFile(overlay, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
+
+// diffValues returns the values which are missing or different in the base values
+func diffValues(base, values map[string]interface{}) map[string]interface{} {
+	answer := map[string]interface{}{}
+	for k, v := range values {
+		baseValue, exists := base[k]
+		if exists && reflect.DeepEqual(baseValue, v) {
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if ok {
+			baseMap, ok := baseValue.(map[string]interface{})
+			if ok {
+				diff := diffValues(baseMap, m)
+				if len(diff) > 0 {
+					answer[k] = diff
+				}
+				continue
+			}
+		}
+		answer[k] = v
+	}
+	return answer
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// GrafanaDashboardFile the name of the Grafana dashboard generated for each server
+	GrafanaDashboardFile = "dashboard.json"
+
+	// DefaultGrafanaDatasource the default Grafana datasource of the Jenkins metrics
+	DefaultGrafanaDatasource = "Prometheus"
+)
+
+// writeGrafanaDashboard renders the Grafana dashboard of the metrics of the jobs of the server
+func (o *Options) writeGrafanaDashboard(server string, configs []*JenkinsTemplateConfig) error {
+	var jobs []string
+	for _, c := range configs {
+		jobs = append(jobs, c.Key)
+	}
+	templateData := map[string]interface{}{
+		"Server":            server,
+		"Jobs":              jobs,
+		"GrafanaDatasource": o.GrafanaDatasource,
+	}
+	path := filepath.Join(o.OutDir, "grafana", server, GrafanaDashboardFile)
+	err := o.renderTemplate(o.GrafanaTemplateDir, GrafanaDashboardFile+".gotmpl", path, templateData)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate Grafana dashboard")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+	if !json.Valid(data) {
+		return errors.Errorf("the generated Grafana dashboard %s is not valid JSON", path)
+	}
+	return nil
+}
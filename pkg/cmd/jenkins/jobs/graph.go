@@ -0,0 +1,194 @@
+package jobs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/awalterschulze/gographviz"
+	"github.com/jenkins-x/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-gitops/pkg/sourceconfigs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphLong = templates.LongDesc(`
+		Generates a DOT graph of the source config, repositories, XML templates and Jenkins servers
+
+The graph can be rendered via graphviz to see which templates are used by which repositories and servers.
+`)
+
+	graphExample = templates.Examples(`
+		# generate the graph to the console
+		%s jenkins jobs graph
+
+		# render the graph as an image
+		%s jenkins jobs graph --graph-file jobs.dot && dot -Tpng jobs.dot -o jobs.png
+	`)
+)
+
+// GraphOptions the options for the graph command
+type GraphOptions struct {
+	Options
+	GraphFile string
+	Out       io.Writer
+}
+
+// NewCmdJenkinsJobsGraph creates a command object for the command
+func NewCmdJenkinsJobsGraph() (*cobra.Command, *GraphOptions) {
+	o := &GraphOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "graph",
+		Short:   "Generates a DOT graph of the source config, repositories, XML templates and Jenkins servers",
+		Long:    graphLong,
+		Example: fmt.Sprintf(graphExample, rootcmd.BinaryName, rootcmd.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the current working directory")
+	cmd.Flags().StringVarP(&o.ConfigFile, "config", "c", "", "the configuration file to load for the repository configurations. If not specified we look in ./.jx/gitops/source-config.yaml")
+	cmd.Flags().StringVarP(&o.DefaultXmlTemplate, "default-xml-template", "", "", "the default XML template file if none is configured for a repository")
+	cmd.Flags().StringVarP(&o.GraphFile, "graph-file", "", "", "the file to write the DOT graph to. If not specified the graph is written to the console")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *GraphOptions) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	graph, err := o.CreateGraph()
+	if err != nil {
+		return errors.Wrapf(err, "failed to create graph")
+	}
+	ast, err := graph.WriteAst()
+	if err != nil {
+		return errors.Wrapf(err, "failed to write graph")
+	}
+	text := ast.String()
+
+	if o.GraphFile == "" {
+		if o.Out == nil {
+			o.Out = os.Stdout
+		}
+		_, err = fmt.Fprint(o.Out, text)
+		return err
+	}
+	err = ioutil.WriteFile(o.GraphFile, []byte(text), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", o.GraphFile)
+	}
+	log.Logger().Infof("created graph file %s", info(o.GraphFile))
+	return nil
+}
+
+// CreateGraph creates the graph of the source config, repositories, XML templates and Jenkins servers
+func (o *GraphOptions) CreateGraph() (*gographviz.Escape, error) {
+	root := filepath.Base(o.ConfigFile)
+	edges := map[graphEdge]bool{}
+	templateRepositories := map[string]int{}
+	nodes := map[string]bool{root: true}
+
+	config := &o.SourceConfig
+	for i := range config.Spec.Groups {
+		group := &config.Spec.Groups[i]
+		for j := range group.Repositories {
+			repo := &group.Repositories[j]
+			sourceconfigs.DefaultValues(config, group, repo)
+
+			repoNode := "repository: " + group.Owner + "/" + repo.Name
+			nodes[repoNode] = true
+			edges[graphEdge{root, repoNode}] = true
+
+			jc := repo.Jenkins
+			if jc == nil {
+				continue
+			}
+			xmlTemplate := o.xmlTemplatePath(jc)
+			if xmlTemplate == "" {
+				continue
+			}
+			rel, err := filepath.Rel(o.Dir, xmlTemplate)
+			if err == nil && !strings.HasPrefix(rel, "..") {
+				xmlTemplate = rel
+			}
+			templateNode := "template: " + xmlTemplate
+			edges[graphEdge{repoNode, templateNode}] = true
+			templateRepositories[templateNode]++
+
+			if jc.Server != "" {
+				serverNode := "server: " + jc.Server
+				nodes[serverNode] = true
+				edges[graphEdge{templateNode, serverNode}] = true
+			}
+		}
+	}
+
+	graph := gographviz.NewEscape()
+	err := graph.SetName("jenkins")
+	if err != nil {
+		return nil, err
+	}
+	err = graph.SetDir(true)
+	if err != nil {
+		return nil, err
+	}
+	err = graph.AddAttr("jenkins", "rankdir", "LR")
+	if err != nil {
+		return nil, err
+	}
+	for node := range nodes {
+		err = graph.AddNode("jenkins", node, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to add node %s", node)
+		}
+	}
+	for node, count := range templateRepositories {
+		attrs := map[string]string{
+			"shape": "note",
+			"label": fmt.Sprintf("%s (%d)", node, count),
+		}
+		err = graph.AddNode("jenkins", node, attrs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to add node %s", node)
+		}
+	}
+
+	// edges are written in the order they are added so lets sort them for a stable output
+	var sortedEdges []graphEdge
+	for edge := range edges {
+		sortedEdges = append(sortedEdges, edge)
+	}
+	sort.Slice(sortedEdges, func(i, j int) bool {
+		if sortedEdges[i].From != sortedEdges[j].From {
+			return sortedEdges[i].From < sortedEdges[j].From
+		}
+		return sortedEdges[i].To < sortedEdges[j].To
+	})
+	for _, edge := range sortedEdges {
+		err = graph.AddEdge(edge.From, edge.To, true, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to add edge from %s to %s", edge.From, edge.To)
+		}
+	}
+	return graph, nil
+}
+
+type graphEdge struct {
+	From string
+	To   string
+}
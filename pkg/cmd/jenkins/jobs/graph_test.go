@@ -0,0 +1,32 @@
+package jobs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/awalterschulze/gographviz"
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJenkinsJobsGraph(t *testing.T) {
+	_, o := jobs.NewCmdJenkinsJobsGraph()
+	o.Dir = "test_data"
+	out := &bytes.Buffer{}
+	o.Out = out
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run the graph command")
+
+	text := out.String()
+	t.Logf("generated graph:\n%s\n", text)
+
+	assert.Contains(t, text, `"source-config.yaml"->"repository: myorg/myapp"`)
+	assert.Contains(t, text, `"repository: myorg/myapp"->"template: jenkins/templates/default.xml.gotmpl"`)
+	assert.Contains(t, text, `"template: jenkins/templates/default.xml.gotmpl"->"server: myjenkins"`)
+	assert.Contains(t, text, `label="template: jenkins/templates/default.xml.gotmpl (2)"`)
+
+	_, err = gographviz.Read([]byte(text))
+	require.NoError(t, err, "failed to parse the generated graph")
+}
@@ -0,0 +1,238 @@
+package jobs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	indexLong = templates.LongDesc(`
+		Packages the helm chart of each Jenkins server and generates a chart repository index
+
+The generated output directory can then be served statically as a helm chart repository. The servers should be generated with the --chart-name flag of the jenkins jobs command so that each server directory is a helm chart. Only the chart files, such as the Chart.yaml, values files and templates, are packaged.
+`)
+
+	indexExample = templates.Examples(`
+		# package the charts and generate the index in the jenkins dir
+		%s jenkins jobs index
+
+		# generate the index.yaml file used by helm for charts served from a URL
+		%s jenkins jobs index --index-file index.yaml --url https://myorg.github.io/jenkins-charts
+	`)
+)
+
+// DefaultIndexFile the default file name of the generated chart index
+const DefaultIndexFile = "chart-index.yaml"
+
+// IndexOptions the options for the index command
+type IndexOptions struct {
+	Dir       string
+	URL       string
+	IndexFile string
+}
+
+// NewCmdJenkinsJobsIndex creates a command object for the command
+func NewCmdJenkinsJobsIndex() (*cobra.Command, *IndexOptions) {
+	o := &IndexOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "index",
+		Short:   "Packages the helm chart of each Jenkins server and generates a chart repository index",
+		Long:    indexLong,
+		Example: fmt.Sprintf(indexExample, rootcmd.BinaryName, rootcmd.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", "jenkins", "the output directory of the jenkins jobs command containing a directory for each server")
+	cmd.Flags().StringVarP(&o.URL, "url", "u", "", "the base URL the chart packages are served from")
+	cmd.Flags().StringVarP(&o.IndexFile, "index-file", "", DefaultIndexFile, "the name of the generated index file in the directory")
+	return cmd, o
+}
+
+// ChartIndex a helm chart repository index
+type ChartIndex struct {
+	APIVersion string                        `json:"apiVersion"`
+	Entries    map[string][]*ChartIndexEntry `json:"entries"`
+	Generated  time.Time                     `json:"generated"`
+}
+
+// ChartIndexEntry a packaged chart version in a helm chart repository index
+type ChartIndexEntry struct {
+	ChartMetadata
+	Created time.Time `json:"created"`
+	Digest  string    `json:"digest"`
+	URLs    []string  `json:"urls"`
+}
+
+// Run implements the command
+func (o *IndexOptions) Run() error {
+	fileInfos, err := ioutil.ReadDir(o.Dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read dir %s", o.Dir)
+	}
+
+	now := time.Now()
+	index := &ChartIndex{
+		APIVersion: "v1",
+		Entries:    map[string][]*ChartIndexEntry{},
+		Generated:  now,
+	}
+	servers := map[string]string{}
+	for _, f := range fileInfos {
+		if !f.IsDir() {
+			continue
+		}
+		server := f.Name()
+		chartDir := filepath.Join(o.Dir, server)
+		chartFile := filepath.Join(chartDir, "Chart.yaml")
+		exists, err := files.FileExists(chartFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if file exists %s", chartFile)
+		}
+		if !exists {
+			log.Logger().Debugf("ignoring dir %s as it has no Chart.yaml", chartDir)
+			continue
+		}
+
+		chart := ChartMetadata{}
+		err = yamls.LoadFile(chartFile, &chart)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load file %s", chartFile)
+		}
+		if chart.Name == "" || chart.Version == "" {
+			return errors.Errorf("the chart file %s should have a name and version", chartFile)
+		}
+		fileName := chart.Name + "-" + chart.Version + ".tgz"
+		if servers[fileName] != "" {
+			return errors.Errorf("servers %s and %s both have chart %s version %s. Each server should use a different chart name", servers[fileName], server, chart.Name, chart.Version)
+		}
+		servers[fileName] = server
+
+		path := filepath.Join(o.Dir, fileName)
+		digest, err := packageDir(chartDir, chart.Name, path, isChartFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to package chart %s", chartDir)
+		}
+		log.Logger().Infof("created chart package %s", info(path))
+
+		url := fileName
+		if o.URL != "" {
+			url = stringhelpers.UrlJoin(o.URL, fileName)
+		}
+		index.Entries[chart.Name] = append(index.Entries[chart.Name], &ChartIndexEntry{
+			ChartMetadata: chart,
+			Created:       now,
+			Digest:        digest,
+			URLs:          []string{url},
+		})
+	}
+
+	path := filepath.Join(o.Dir, o.IndexFile)
+	err = yamls.SaveFile(index, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	log.Logger().Infof("created chart index %s", info(path))
+	return nil
+}
+
+// chartFiles the files in the root of a chart dir which are included in the chart package
+var chartFiles = []string{"Chart.yaml", "values.yaml", "values.schema.json", "README.md", "LICENSE", ".helmignore"}
+
+// chartDirs the dirs of a chart dir which are included in the chart package
+var chartDirs = []string{"templates", "charts", "crds"}
+
+// isChartFile returns true if the file relative to the chart dir is part of the helm chart. The other generated files,
+// such as the credentials, diffs and compressed values, are not packaged
+func isChartFile(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, dir := range chartDirs {
+		if strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	if strings.HasPrefix(rel, "values-") && strings.HasSuffix(rel, ".yaml") && !strings.Contains(rel, "/") {
+		return true
+	}
+	return stringhelpers.StringArrayIndex(chartFiles, rel) >= 0
+}
+
+// packageDir writes the files in the dir to a gzipped tar archive under the given name dir returning the SHA256 digest of the archive.
+// If the name is empty the files are written to the root of the archive. If include is specified only the files relative
+// to the dir which it returns true for are written
+func packageDir(chartDir, name, path string, include func(rel string) bool) (string, error) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	err := filepath.Walk(chartDir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(chartDir, file)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find relative path of %s", file)
+		}
+		if include != nil && !include(rel) {
+			return nil
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load file %s", file)
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:    filepath.ToSlash(filepath.Join(name, rel)),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: fi.ModTime(),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to write header for %s", file)
+		}
+		_, err = tw.Write(data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write %s", file)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	err = tw.Close()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to close tar archive")
+	}
+	err = gz.Close()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to close gzip archive")
+	}
+
+	data := buf.Bytes()
+	err = ioutil.WriteFile(path, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
@@ -0,0 +1,83 @@
+package jobs_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJenkinsJobsIndex(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, jo := jobs.NewCmdJenkinsJobs()
+	jo.OutDir = tmpDir
+	jo.Dir = "test_data"
+	jo.ChartName = "myjenkins-jobs"
+	jo.ChartVersion = "1.2.3"
+	err = jo.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	serverDir := filepath.Join(tmpDir, "myjenkins")
+	for _, name := range []string{"changes.diff", "values.yaml.gz", "values-staging.yaml", filepath.Join("templates", "extra-cm.yaml")} {
+		path := filepath.Join(serverDir, name)
+		err = os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir for %s", path)
+		err = ioutil.WriteFile(path, []byte("test: true\n"), files.DefaultFileWritePermissions)
+		require.NoError(t, err, "failed to save file %s", path)
+	}
+
+	_, o := jobs.NewCmdJenkinsJobsIndex()
+	o.Dir = tmpDir
+	o.URL = "https://charts.example.com"
+	err = o.Run()
+	require.NoError(t, err, "failed to run the index command in dir %s", tmpDir)
+
+	chartFile := filepath.Join(tmpDir, "myjenkins-jobs-1.2.3.tgz")
+	require.FileExists(t, chartFile, "should have packaged the chart")
+	f, err := os.Open(chartFile)
+	require.NoError(t, err, "failed to open %s", chartFile)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err, "failed to read %s", chartFile)
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "failed to read %s", chartFile)
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+	expected := []string{
+		"myjenkins-jobs/Chart.yaml",
+		"myjenkins-jobs/templates/extra-cm.yaml",
+		"myjenkins-jobs/values-staging.yaml",
+		"myjenkins-jobs/values.yaml",
+	}
+	assert.Equal(t, expected, names, "files in chart package %s", chartFile)
+
+	path := filepath.Join(tmpDir, jobs.DefaultIndexFile)
+	index := &jobs.ChartIndex{}
+	err = yamls.LoadFile(path, index)
+	require.NoError(t, err, "failed to load index %s", path)
+	assert.Equal(t, "v1", index.APIVersion, "index apiVersion")
+	require.Len(t, index.Entries["myjenkins-jobs"], 1, "chart entries in index %s", path)
+	cv := index.Entries["myjenkins-jobs"][0]
+	assert.Equal(t, "1.2.3", cv.Version, "chart version")
+	assert.Equal(t, []string{"https://charts.example.com/myjenkins-jobs-1.2.3.tgz"}, cv.URLs, "chart URLs")
+	assert.NotEmpty(t, cv.Digest, "chart digest")
+}
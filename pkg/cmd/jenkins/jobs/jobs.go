@@ -1,24 +1,39 @@
 package jobs
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/Masterminds/sprig"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
 	"github.com/jenkins-x/jx-gitops/pkg/apis/gitops/v1alpha1"
 	"github.com/jenkins-x/jx-gitops/pkg/rootcmd"
 	"github.com/jenkins-x/jx-gitops/pkg/sourceconfigs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/templater"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/pkg/errors"
+	"github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/yaml"
 )
 
@@ -36,14 +51,110 @@ var (
 	`)
 )
 
+const (
+	// ManagedByLabel the label added to all generated resources
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+
+	// ManagedByValue the value of the ManagedByLabel
+	ManagedByValue = "jx-gitops"
+
+	// GitLabTokenEnvVar the environment variable containing the GitLab token used to lookup GitLab project IDs
+	GitLabTokenEnvVar = "GITLAB_TOKEN"
+
+	// DefaultMaxTemplateSize the default maximum size in bytes of an XML template
+	DefaultMaxTemplateSize = 1024 * 1024
+
+	// DefaultNexusIQStage the default Nexus IQ stage of the policy evaluation
+	DefaultNexusIQStage = "build"
+
+	// NexusIQAppsFile the file listing the Nexus IQ applications of the jobs of a server
+	NexusIQAppsFile = "nexus-iq-apps.yaml"
+
+	// DefaultScaledJobMaxReplicas the default maximum number of build agent pods of a KEDA ScaledJob
+	DefaultScaledJobMaxReplicas = 10
+
+	// ScaledJobsFile the file containing the KEDA ScaledJobs of the jobs of a server
+	ScaledJobsFile = "scaled-jobs.yaml"
+
+	// DefaultKeptnStage the default Keptn stage the quality gate is evaluated in
+	DefaultKeptnStage = "production"
+)
+
+// ChartMetadata the metadata of a generated helm chart
+type ChartMetadata struct {
+	APIVersion  string `json:"apiVersion"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
 // LabelOptions the options for the command
 type Options struct {
-	Dir                string
-	ConfigFile         string
-	OutDir             string
-	DefaultXmlTemplate string
-	SourceConfig       v1alpha1.SourceConfig
-	JenkinsServers     map[string][]*JenkinsTemplateConfig
+	Dir                         string
+	ConfigFile                  string
+	OutDir                      string
+	DefaultXmlTemplate          string
+	WoodpeckerTemplateDir       string
+	DroneTemplateDir            string
+	AzureDevOpsTemplateDir      string
+	BuildkiteTemplateDir        string
+	SemaphoreTemplateDir        string
+	GitLabCITemplateDir         string
+	CircleCITemplateDir         string
+	HarnessTemplateDir          string
+	BitbucketPipelinesDir       string
+	TeamCityTemplateDir         string
+	CrossplaneTemplateDir       string
+	OPAPolicyTemplateDir        string
+	SpinnakerTemplateDir        string
+	SpinnakerGateURL            string
+	KeptnTemplateDir            string
+	KEDATemplateDir             string
+	GrafanaTemplateDir          string
+	GrafanaDatasource           string
+	GitLabToken                 string
+	GitLabTokenFile             string
+	GitLabClients               map[string]*scm.Client
+	PulumiTemplateDir           string
+	MaxTemplateSize             int64
+	HelmChartVersion            string
+	OutputCompression           string
+	FluxKustomizationTemplate   string
+	TektonEventListenerTemplate string
+	BackstageTemplate           string
+	GitHubRepoSettingsTemplate  string
+	RenovateTemplate            string
+	ChartName                   string
+	ChartVersion                string
+	ChartDescription            string
+	S3Bucket                    string
+	AWSRegion                   string
+	AWSProfile                  string
+	AWSSecretARN                string
+	EmitDiffs                   bool
+	Merge                       bool
+	EmitConfigMap               bool
+	TemplateLint                bool
+	LintStrict                  bool
+	Labels                      []string
+	MaskKeys                    []string
+	CredentialsConfigMap        string
+	Env                         string
+	EnvValuesFile               string
+	KubeClient                  kubernetes.Interface
+	HTTPClient                  *http.Client
+	S3Client                    S3GetObjectAPI
+	SecretsManagerClient        SecretsManagerGetSecretValueAPI
+	SourceConfig                v1alpha1.SourceConfig
+	JenkinsServers              map[string][]*JenkinsTemplateConfig
+	s3Templates                 map[string]string
+	labels                      map[string]string
+	credentialValues            map[string]string
+	envValues                   map[string]interface{}
+	valuesKey                   string
+	mergeDir                    string
+	lintedTemplates             map[string]bool
+	serverCredentials           []v1alpha1.CredentialConfig
 }
 
 // JenkinsTemplateConfig stores the data to render jenkins config files
@@ -53,6 +164,10 @@ type JenkinsTemplateConfig struct {
 	XMLTemplateFile string
 	XMLTemplateText string
 	TemplateData    map[string]interface{}
+	SharedLibraries []v1alpha1.SharedLibraryConfig
+	GlobalLibraries []v1alpha1.SharedLibraryConfig
+	NexusIQ         *v1alpha1.NexusConfig
+	KEDA            *v1alpha1.KEDAConfig
 }
 
 // NewCmdJenkinsJobs creates a command object for the command
@@ -70,14 +185,106 @@ func NewCmdJenkinsJobs() (*cobra.Command, *Options) {
 			helper.CheckErr(err)
 		},
 	}
+	cmd.AddCommand(cobras.SplitCommand(NewCmdJenkinsJobsGraph()))
+	cmd.AddCommand(cobras.SplitCommand(NewCmdJenkinsJobsValidateLive()))
+	cmd.AddCommand(cobras.SplitCommand(NewCmdJenkinsJobsDeps()))
+	cmd.AddCommand(cobras.SplitCommand(NewCmdJenkinsJobsIndex()))
+	cmd.AddCommand(cobras.SplitCommand(NewCmdJenkinsJobsPR()))
+
 	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the current working directory")
+	o.AddFlags(cmd)
+	return cmd, o
+}
+
+// AddFlags adds the flags used to generate the Jenkins jobs
+func (o *Options) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&o.OutDir, "out", "o", "", "the output directory for the generated config files. If not specified defaults to the jenkins dir in the current directory")
 	cmd.Flags().StringVarP(&o.ConfigFile, "config", "c", "", "the configuration file to load for the repository configurations. If not specified we look in ./.jx/gitops/source-config.yaml")
 	cmd.Flags().StringVarP(&o.DefaultXmlTemplate, "default-xml-template", "", "", "the default XML template file if none is configured for a repository")
-	return cmd, o
+	cmd.Flags().StringVarP(&o.WoodpeckerTemplateDir, "woodpecker-template-dir", "", "", "the directory containing the .woodpecker.yaml.gotmpl template used to generate Woodpecker CI pipelines for gitea and github repositories")
+	cmd.Flags().StringVarP(&o.FluxKustomizationTemplate, "flux-kustomization-template", "", "", "the template file used to generate a Flux Kustomization for each Jenkins server in the flux directory")
+	cmd.Flags().StringVarP(&o.TektonEventListenerTemplate, "tekton-eventlistener-template", "", "", "the template file used to generate a Tekton EventListener for each repository in the tekton directory. The interceptor for the git provider is available as .TektonTriggerType")
+	cmd.Flags().StringVarP(&o.BackstageTemplate, "backstage-template", "", "", "the template file used to generate a Backstage catalog-info.yaml Component for each repository in the backstage directory")
+	cmd.Flags().StringVarP(&o.GitHubRepoSettingsTemplate, "github-repo-settings-template", "", "", "the template file used to generate a probot settings .github/settings.yml file for each github repository in the github-settings directory")
+	cmd.Flags().StringVarP(&o.RenovateTemplate, "renovate-template", "", "", "the template file used to generate a renovate.json file for each repository group in the renovate directory. The group language and dependency manager are available as .Language and .DependencyManager")
+	cmd.Flags().StringVarP(&o.HelmChartVersion, "helm-chart-version", "", "", fmt.Sprintf("the version of the Jenkins helm chart the values are generated for such as %s. Versions 3.0.0 and later use the controller rather than the master values key. If not specified the master values key is used", LatestHelmChartVersion))
+	cmd.Flags().Int64VarP(&o.MaxTemplateSize, "max-template-size", "", DefaultMaxTemplateSize, "the maximum size in bytes of an XML template file. Larger templates are rejected")
+	cmd.Flags().StringVarP(&o.PulumiTemplateDir, "pulumi-template", "", "", "the directory containing the *.gotmpl files of a Pulumi program used to provision the helm release of each Jenkins server. The file names are also templates so Pulumi.{{ .Server }}.yaml.gotmpl generates a stack file per server")
+	cmd.Flags().StringVarP(&o.BuildkiteTemplateDir, "buildkite-template-dir", "", "", "the directory containing the pipeline.yml.gotmpl template used to generate Buildkite pipelines for github repositories with buildkite configuration")
+	cmd.Flags().StringVarP(&o.GitLabCITemplateDir, "gitlab-ci-template-dir", "", "", "the directory containing the .gitlab-ci.yml.gotmpl template used to generate GitLab CI pipelines for gitlab repositories")
+	cmd.Flags().StringVarP(&o.GitLabTokenFile, "gitlab-token-file", "", "", "the file containing the GitLab token used to lookup the project ID of gitlab repositories which is available in the templates as .GitLabProjectID. If not specified the $"+GitLabTokenEnvVar+" environment variable is used")
+	cmd.Flags().StringVarP(&o.CircleCITemplateDir, "circleci-template-dir", "", "", "the directory containing the config.yml.gotmpl template used to generate CircleCI configurations for github and bitbucket repositories")
+	cmd.Flags().StringVarP(&o.HarnessTemplateDir, "harness-template-dir", "", "", "the directory containing the pipeline.yaml.gotmpl template used to generate Harness CI pipelines for github repositories with harness configuration")
+	cmd.Flags().StringVarP(&o.BitbucketPipelinesDir, "bitbucket-pipelines-template-dir", "", "", "the directory containing the bitbucket-pipelines.yml.gotmpl template used to generate Bitbucket Pipelines configurations for bitbucket repositories")
+	cmd.Flags().StringVarP(&o.TeamCityTemplateDir, "teamcity-template-dir", "", "", "the directory containing the settings.kts.gotmpl template used to generate TeamCity Kotlin DSL build configurations for repositories with teamcity configuration")
+	cmd.Flags().StringVarP(&o.CrossplaneTemplateDir, "crossplane-template-dir", "", "", "the directory containing the <kind>.yaml.gotmpl templates used to generate the Crossplane claims of repositories with infra configuration")
+	cmd.Flags().StringVarP(&o.OPAPolicyTemplateDir, "opa-policy-template-dir", "", "", "the directory containing the *.rego.gotmpl templates used to generate an OPA policy bundle of the jobs of each Jenkins server")
+	cmd.Flags().StringVarP(&o.SpinnakerTemplateDir, "spinnaker-template-dir", "", "", "the directory containing the pipeline.json.gotmpl template used to generate Spinnaker pipelines for repositories with spinnaker configuration")
+	cmd.Flags().StringVarP(&o.SpinnakerGateURL, "spinnaker-gate-url", "", "", "the URL of the Spinnaker Gate API. If specified the generated Spinnaker pipelines are saved using the API")
+	cmd.Flags().StringVarP(&o.KeptnTemplateDir, "keptn-template-dir", "", "", "the directory containing the slo.yaml.gotmpl and sli.yaml.gotmpl templates used to generate the Keptn quality gate files of repositories with keptn configuration")
+	cmd.Flags().StringVarP(&o.KEDATemplateDir, "keda-template-dir", "", "", "the directory containing the scaled-job.yaml.gotmpl template used to generate the KEDA ScaledJobs which auto scale the build agents of jobs with keda configuration")
+	cmd.Flags().StringVarP(&o.GrafanaTemplateDir, "grafana-template-dir", "", "", "the directory containing the dashboard.json.gotmpl template used to generate a Grafana dashboard of the build, queue and agent metrics of the jobs of each Jenkins server")
+	cmd.Flags().StringVarP(&o.GrafanaDatasource, "grafana-datasource", "", DefaultGrafanaDatasource, "the Grafana datasource of the Jenkins metrics used in the generated dashboards")
+	cmd.Flags().StringVarP(&o.SemaphoreTemplateDir, "semaphore-template-dir", "", "", "the directory containing the semaphore.yml.gotmpl template used to generate Semaphore CI pipelines for github repositories with semaphore configuration")
+	cmd.Flags().StringVarP(&o.AzureDevOpsTemplateDir, "azuredevops-template-dir", "", "", "the directory containing the azure-pipelines.yml.gotmpl template used to generate Azure DevOps pipelines for azure repositories")
+	cmd.Flags().StringVarP(&o.DroneTemplateDir, "drone-template-dir", "", "", "the directory containing the .drone.yml.gotmpl template used to generate Drone CI pipelines for gitea and github repositories")
+	cmd.Flags().StringVarP(&o.ChartName, "chart-name", "", "", "if specified a Chart.yaml file with this chart name is generated for each server so that the output directory is a complete helm chart")
+	cmd.Flags().StringVarP(&o.ChartVersion, "chart-version", "", "0.0.1", "the version of the generated Chart.yaml files")
+	cmd.Flags().StringVarP(&o.ChartDescription, "chart-description", "", "", "the description of the generated Chart.yaml files")
+	cmd.Flags().StringVarP(&o.S3Bucket, "s3-bucket", "", "", "the default S3 bucket used for XML templates of the form s3:///path/to/template")
+	cmd.Flags().StringVarP(&o.AWSRegion, "aws-region", "", "", "the AWS region used to access S3 and Secrets Manager. If not specified the standard AWS configuration is used")
+	cmd.Flags().StringVarP(&o.AWSProfile, "aws-profile", "", "", "the AWS profile used to access S3 and Secrets Manager. If not specified the standard AWS configuration is used")
+	cmd.Flags().BoolVarP(&o.EmitConfigMap, "emit-configmap", "", false, "if enabled a ConfigMap containing the job XML configurations is generated for each server")
+	cmd.Flags().BoolVarP(&o.TemplateLint, "template-lint", "", false, "if enabled each XML template is checked for syntax errors, undefined or dangerous functions and always empty output before it is rendered")
+	cmd.Flags().BoolVarP(&o.LintStrict, "lint-strict", "", false, "if enabled any issues found by --template-lint fail the command rather than being logged as warnings")
+	cmd.Flags().StringArrayVarP(&o.Labels, "label", "l", nil, "the labels of the form key=value to add to the generated resources")
+	cmd.Flags().StringSliceVarP(&o.MaskKeys, "mask-keys", "", nil, fmt.Sprintf("the template data keys whose values are replaced with %s in the log output. Keys containing any of %s are always masked", MaskedValue, strings.Join(DefaultMaskPatterns, ", ")))
+	cmd.Flags().BoolVarP(&o.Merge, "merge", "", false, "if enabled the generated jobs are merged into any existing values.yaml file preserving any other values")
+	cmd.Flags().StringVarP(&o.Env, "env", "", "", "the name of an environment. If specified a values-<env>.yaml file is written for each server containing only the --env-values which differ from the base values.yaml")
+	cmd.Flags().StringVarP(&o.EnvValuesFile, "env-values", "", "", "the values YAML file of the --env environment")
+	cmd.Flags().StringVarP(&o.CredentialsConfigMap, "credentials-configmap", "", "", "an optional ConfigMap of the form namespace/name whose entries are available in the templates as .Credentials.<key>")
+	cmd.Flags().StringVarP(&o.AWSSecretARN, "aws-secret-arn", "", "", "if specified the source config YAML is loaded from this AWS Secrets Manager secret rather than the --config file")
+	cmd.Flags().StringVarP(&o.OutputCompression, "output-compression", "", "", fmt.Sprintf("if specified the values files are compressed. Supported values: %s", strings.Join(OutputCompressions, ", ")))
+	cmd.Flags().BoolVarP(&o.EmitDiffs, "emit-diffs", "", false, "if enabled a changes.diff file is written next to each modified values.yaml file describing the changes")
 }
 
 func (o *Options) Validate() error {
+	o.labels = map[string]string{
+		ManagedByLabel: ManagedByValue,
+	}
+	for _, l := range o.Labels {
+		values := strings.SplitN(l, "=", 2)
+		if len(values) != 2 || values[0] == "" {
+			return options.InvalidOptionf("label", l, "labels should be of the form key=value")
+		}
+		o.labels[values[0]] = values[1]
+	}
+
+	var err error
+	o.valuesKey = DefaultValuesKey
+	if o.HelmChartVersion != "" {
+		o.valuesKey, err = ValuesKeyForChartVersion(o.HelmChartVersion)
+		if err != nil {
+			return options.InvalidOptionf("helm-chart-version", o.HelmChartVersion, "%s", err.Error())
+		}
+	}
+
+	if o.OutputCompression != "" && stringhelpers.StringArrayIndex(OutputCompressions, o.OutputCompression) < 0 {
+		return options.InvalidOption("output-compression", o.OutputCompression, OutputCompressions)
+	}
+
+	if o.GitLabToken == "" {
+		if o.GitLabTokenFile != "" {
+			data, err := ioutil.ReadFile(o.GitLabTokenFile)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load file %s", o.GitLabTokenFile)
+			}
+			o.GitLabToken = strings.TrimSpace(string(data))
+		} else {
+			o.GitLabToken = os.Getenv(GitLabTokenEnvVar)
+		}
+	}
+
 	if o.ConfigFile == "" {
 		o.ConfigFile = filepath.Join(o.Dir, ".jx", "gitops", v1alpha1.SourceConfigFileName)
 	}
@@ -85,16 +292,18 @@ func (o *Options) Validate() error {
 		o.OutDir = filepath.Join(o.Dir, "jenkins")
 	}
 
-	exists, err := files.FileExists(o.ConfigFile)
-	if err != nil {
-		return errors.Wrapf(err, "failed to check if file exists %s", o.ConfigFile)
-	}
-	if !exists {
-		log.Logger().Infof("the source config file %s does not exist", info(o.ConfigFile))
-		return nil
+	if o.AWSSecretARN == "" {
+		exists, err := files.FileExists(o.ConfigFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if file exists %s", o.ConfigFile)
+		}
+		if !exists {
+			log.Logger().Infof("the source config file %s does not exist", info(o.ConfigFile))
+			return nil
+		}
 	}
 
-	if o.DefaultXmlTemplate != "" {
+	if o.DefaultXmlTemplate != "" && !isS3URL(o.DefaultXmlTemplate) {
 		exists, err := files.FileExists(o.DefaultXmlTemplate)
 		if err != nil {
 			return errors.Wrapf(err, "failed to check if file exists %s", o.DefaultXmlTemplate)
@@ -104,14 +313,33 @@ func (o *Options) Validate() error {
 		}
 	}
 
-	err = yamls.LoadFile(o.ConfigFile, &o.SourceConfig)
-	if err != nil {
-		return errors.Wrapf(err, "failed to load file %s", o.ConfigFile)
+	if o.AWSSecretARN != "" {
+		err := o.loadSourceConfigFromSecret()
+		if err != nil {
+			return errors.Wrapf(err, "failed to load the source config from AWS secret %s", o.AWSSecretARN)
+		}
+	} else {
+		err := yamls.LoadFile(o.ConfigFile, &o.SourceConfig)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load file %s", o.ConfigFile)
+		}
+	}
+
+	if o.Env != "" {
+		if o.EnvValuesFile == "" {
+			return options.MissingOption("env-values")
+		}
+		o.envValues = map[string]interface{}{}
+		err := yamls.LoadFile(o.EnvValuesFile, &o.envValues)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load file %s", o.EnvValuesFile)
+		}
 	}
 
 	if o.JenkinsServers == nil {
 		o.JenkinsServers = map[string][]*JenkinsTemplateConfig{}
 	}
+	o.lintedTemplates = map[string]bool{}
 	return nil
 }
 
@@ -121,72 +349,488 @@ func (o *Options) Run() error {
 		return errors.Wrapf(err, "failed to validate options")
 	}
 
+	err = o.loadCredentialsConfigMap()
+	if err != nil {
+		return errors.Wrapf(err, "failed to load credentials ConfigMap %s", o.CredentialsConfigMap)
+	}
+
 	config := &o.SourceConfig
 	for i := range config.Spec.Groups {
 		group := &config.Spec.Groups[i]
 		for j := range group.Repositories {
 			repo := &group.Repositories[j]
 			sourceconfigs.DefaultValues(config, group, repo)
-			if repo.Jenkins == nil {
-				continue
+			err = o.processRepository(group, repo)
+			if err != nil {
+				return errors.Wrapf(err, "failed to process repository %s", repo.URL)
 			}
-			err = o.processJenkinsConfig(group, repo, repo.Jenkins)
+		}
+
+		if o.RenovateTemplate != "" {
+			err = o.writeRenovateConfig(group)
 			if err != nil {
-				return errors.Wrapf(err, "failed to process Jenkins Config")
+				return errors.Wrapf(err, "failed to generate Renovate configuration for owner %s", group.Owner)
 			}
 		}
 	}
 
 	for server, configs := range o.JenkinsServers {
-		dir := filepath.Join(o.OutDir, server)
-		err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+		err = o.generateServer(server, configs)
 		if err != nil {
-			return errors.Wrapf(err, "failed to create dir %s", dir)
+			return errors.Wrapf(err, "failed to generate files for Jenkins server %s", server)
 		}
-		path := filepath.Join(dir, "values.yaml")
-		log.Logger().Infof("creating Jenkins values.yaml file %s", path)
+	}
+	return nil
+}
+
+// generateServer generates the files for the given Jenkins server
+func (o *Options) generateServer(server string, configs []*JenkinsTemplateConfig) error {
+	dir := filepath.Join(o.OutDir, server)
+	err := os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	serverConfig := o.jenkinsServerConfig(server)
+	o.serverCredentials = nil
+	if serverConfig != nil {
+		o.serverCredentials = serverConfig.Credentials
+	}
+	defer func() {
+		o.serverCredentials = nil
+	}()
 
-		funcMap := sprig.TxtFuncMap()
+	funcMap := o.templateFuncMap()
 
-		jobs := map[string]interface{}{}
+	jobs := map[string]interface{}{}
+	jobsXML := map[string]string{}
 
-		for _, jcfg := range configs {
-			output, err := templater.Evaluate(funcMap, jcfg.TemplateData, jcfg.XMLTemplateText, jcfg.XMLTemplateFile, "Jenkins Server "+server)
+	for _, jcfg := range configs {
+		if o.TemplateLint {
+			err = o.lintTemplate(funcMap, jcfg.XMLTemplateFile, jcfg.XMLTemplateText)
 			if err != nil {
-				return errors.Wrapf(err, "failed to evaluate template %s", jcfg.XMLTemplateFile)
+				return errors.Wrapf(err, "failed to lint template %s", jcfg.XMLTemplateFile)
 			}
-			jobs[jcfg.Key] = output
 		}
+		o.logTemplateData(jcfg.XMLTemplateFile, jcfg.TemplateData)
+		output, err := templater.Evaluate(funcMap, jcfg.TemplateData, jcfg.XMLTemplateText, jcfg.XMLTemplateFile, "Jenkins Server "+server)
+		if err != nil {
+			return errors.Wrapf(err, "failed to evaluate template %s", jcfg.XMLTemplateFile)
+		}
+		jobs[jcfg.Key] = output
+		jobsXML[jcfg.Key+".xml"] = output
+	}
 
-		values := map[string]interface{}{
-			"master": map[string]interface{}{
-				"jobs": jobs,
-			},
+	if serverConfig != nil && serverConfig.Format == v1alpha1.JenkinsServerFormatOperator {
+		err = o.writeJenkinsCR(dir, serverConfig, jobs)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write Jenkins custom resource for server %s", server)
+		}
+	} else {
+		err = o.writeValues(dir, server, configs, jobs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(o.serverCredentials) > 0 {
+		err = writeCredentials(dir, o.valuesKey, o.serverCredentials)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write credentials for server %s", server)
+		}
+	}
+
+	if o.OPAPolicyTemplateDir != "" {
+		err = o.writeOPABundle(server, configs)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write OPA policy bundle for server %s", server)
+		}
+	}
+
+	err = writeNexusIQApps(dir, configs)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write Nexus IQ applications for server %s", server)
+	}
+
+	if o.KEDATemplateDir != "" {
+		err = o.writeScaledJobs(server, configs)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write KEDA ScaledJobs for server %s", server)
+		}
+	}
+
+	if o.GrafanaTemplateDir != "" {
+		err = o.writeGrafanaDashboard(server, configs)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write Grafana dashboard for server %s", server)
+		}
+	}
+
+	if o.EmitConfigMap {
+		err = o.writeConfigMap(dir, server, jobsXML)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write ConfigMap for server %s", server)
+		}
+	}
+
+	if o.ChartName != "" {
+		err = o.writeChart(dir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write Chart.yaml for server %s", server)
+		}
+	}
+
+	if o.FluxKustomizationTemplate != "" {
+		err = o.writeFluxKustomization(dir, server)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write Flux Kustomization for server %s", server)
+		}
+	}
+
+	if o.PulumiTemplateDir != "" {
+		err = o.writePulumiProgram(dir, server)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write Pulumi program for server %s", server)
+		}
+	}
+	return nil
+}
+
+// writeFluxKustomization renders the Flux Kustomization which deploys the generated files of the server
+func (o *Options) writeFluxKustomization(dir, server string) error {
+	sourcePath := dir
+	rel, err := filepath.Rel(o.Dir, dir)
+	if err == nil && !strings.HasPrefix(rel, "..") {
+		sourcePath = "./" + filepath.ToSlash(rel)
+	}
+	templateData := map[string]interface{}{
+		"Server": server,
+		"Path":   sourcePath,
+		"Labels": o.labels,
+	}
+	path := filepath.Join(o.OutDir, "flux", server+"-kustomization.yaml")
+	return o.renderTemplate(filepath.Dir(o.FluxKustomizationTemplate), filepath.Base(o.FluxKustomizationTemplate), path, templateData)
+}
+
+func (o *Options) processRepository(group *v1alpha1.RepositoryGroup, repo *v1alpha1.Repository) error {
+	if repo.Jenkins != nil {
+		err := o.processJenkinsConfig(group, repo, repo.Jenkins)
+		if err != nil {
+			return errors.Wrapf(err, "failed to process Jenkins Config")
+		}
+	}
+
+	if o.WoodpeckerTemplateDir != "" && (group.ProviderKind == "gitea" || group.ProviderKind == "github") {
+		path := filepath.Join(o.OutDir, "woodpecker", group.Owner, repo.Name, ".woodpecker.yaml")
+		err := o.renderTemplate(o.WoodpeckerTemplateDir, ".woodpecker.yaml.gotmpl", path, o.createTemplateData(group, repo))
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate Woodpecker CI pipeline")
+		}
+	}
+
+	if o.DroneTemplateDir != "" && (group.ProviderKind == "gitea" || group.ProviderKind == "github") {
+		templateData := o.createTemplateData(group, repo)
+		templateData["DroneRunner"] = "docker"
+		if repo.Drone != nil && repo.Drone.Runner != "" {
+			templateData["DroneRunner"] = repo.Drone.Runner
+		}
+		path := filepath.Join(o.OutDir, "drone", group.Owner, repo.Name, ".drone.yml")
+		err := o.renderTemplate(o.DroneTemplateDir, ".drone.yml.gotmpl", path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate Drone CI pipeline")
+		}
+	}
+
+	if o.AzureDevOpsTemplateDir != "" && group.ProviderKind == "azure" {
+		path := filepath.Join(o.OutDir, "azuredevops", group.Owner, repo.Name, "azure-pipelines.yml")
+		err := o.renderTemplate(o.AzureDevOpsTemplateDir, "azure-pipelines.yml.gotmpl", path, o.createTemplateData(group, repo))
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate Azure DevOps pipeline")
+		}
+	}
+
+	if o.BuildkiteTemplateDir != "" && group.ProviderKind == "github" && repo.Buildkite != nil {
+		templateData := o.createTemplateData(group, repo)
+		templateData["BuildkiteQueue"] = repo.Buildkite.Queue
+		path := filepath.Join(o.OutDir, "buildkite", group.Owner, repo.Name, ".buildkite", "pipeline.yml")
+		err := o.renderTemplate(o.BuildkiteTemplateDir, "pipeline.yml.gotmpl", path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate Buildkite pipeline")
+		}
+	}
+
+	if o.GitLabCITemplateDir != "" && group.ProviderKind == "gitlab" {
+		templateData := o.createTemplateData(group, repo)
+		templateData["GitLabProjectID"] = ""
+		if o.GitLabToken != "" || o.GitLabClients[group.Provider] != nil {
+			projectID, err := o.gitLabProjectID(group, repo)
+			if err != nil {
+				return errors.Wrapf(err, "failed to find the GitLab project ID")
+			}
+			templateData["GitLabProjectID"] = projectID
+		}
+		path := filepath.Join(o.OutDir, "gitlab-ci", group.Owner, repo.Name, ".gitlab-ci.yml")
+		err := o.renderTemplate(o.GitLabCITemplateDir, ".gitlab-ci.yml.gotmpl", path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate GitLab CI pipeline")
+		}
+	}
+
+	circleCIVCS := circleCIVCSType(group.ProviderKind)
+	if o.CircleCITemplateDir != "" && circleCIVCS != "" {
+		templateData := o.createTemplateData(group, repo)
+		templateData["CircleCIOrgSlug"] = circleCIVCS + "/" + group.Owner
+		path := filepath.Join(o.OutDir, "circleci", group.Owner, repo.Name, ".circleci", "config.yml")
+		err := o.renderTemplate(o.CircleCITemplateDir, "config.yml.gotmpl", path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate CircleCI configuration")
+		}
+	}
+
+	if o.HarnessTemplateDir != "" && group.ProviderKind == "github" && repo.Harness != nil {
+		project := repo.Harness.Project
+		if project == "" {
+			project = group.Owner
+		}
+		templateData := o.createTemplateData(group, repo)
+		templateData["HarnessProject"] = project
+		templateData["HarnessOrg"] = repo.Harness.Organization
+		templateData["HarnessIdentifier"] = entityIdentifier(repo.Name)
+		path := filepath.Join(o.OutDir, "harness", project, repo.Name, "pipeline.yaml")
+		err := o.renderTemplate(o.HarnessTemplateDir, "pipeline.yaml.gotmpl", path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate Harness CI pipeline")
+		}
+	}
+
+	if o.BitbucketPipelinesDir != "" && isBitbucket(group.ProviderKind) {
+		templateData := o.createTemplateData(group, repo)
+		templateData["BitbucketWorkspace"] = group.Owner
+		templateData["BitbucketRepoSlug"] = bitbucketRepoSlug(repo.Name)
+		path := filepath.Join(o.OutDir, "bitbucket-pipelines", group.Owner, repo.Name, "bitbucket-pipelines.yml")
+		err := o.renderTemplate(o.BitbucketPipelinesDir, "bitbucket-pipelines.yml.gotmpl", path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate Bitbucket Pipelines configuration")
+		}
+	}
+
+	if o.TeamCityTemplateDir != "" && repo.TeamCity != nil {
+		projectID := repo.TeamCity.ProjectID
+		if projectID == "" {
+			projectID = entityIdentifier(group.Owner)
+		}
+		vcsRootID := repo.TeamCity.VCSRootID
+		if vcsRootID == "" {
+			vcsRootID = projectID + "_" + entityIdentifier(repo.Name)
+		}
+		templateData := o.createTemplateData(group, repo)
+		templateData["TeamCityProjectID"] = projectID
+		templateData["TeamCityVCSRootID"] = vcsRootID
+		path := filepath.Join(o.OutDir, "teamcity", projectID, repo.Name, ".teamcity", "settings.kts")
+		err := o.renderTemplate(o.TeamCityTemplateDir, "settings.kts.gotmpl", path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate TeamCity build configuration")
+		}
+	}
+
+	if o.SemaphoreTemplateDir != "" && group.ProviderKind == "github" && repo.Semaphore != nil {
+		templateData := o.createTemplateData(group, repo)
+		templateData["SemaphoreOrg"] = group.Owner
+		if repo.Semaphore.Organization != "" {
+			templateData["SemaphoreOrg"] = repo.Semaphore.Organization
+		}
+		path := filepath.Join(o.OutDir, "semaphore", group.Owner, repo.Name, ".semaphore", "semaphore.yml")
+		err := o.renderTemplate(o.SemaphoreTemplateDir, "semaphore.yml.gotmpl", path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate Semaphore CI pipeline")
 		}
+	}
+
+	if o.CrossplaneTemplateDir != "" && repo.Infra != nil {
+		for i := range repo.Infra.Claims {
+			claim := &repo.Infra.Claims[i]
+			if claim.Kind == "" {
+				return errors.Errorf("missing kind of Crossplane claim %d", i)
+			}
+			kind := strings.ToLower(claim.Kind)
+			name := claim.Name
+			if name == "" {
+				name = repo.Name + "-" + kind
+			}
+			templateData := o.createTemplateData(group, repo)
+			templateData["Infra"] = repo.Infra
+			templateData["Claim"] = map[string]interface{}{
+				"Kind":       claim.Kind,
+				"Name":       name,
+				"Parameters": claim.Parameters,
+			}
+			path := filepath.Join(o.OutDir, "crossplane", repo.Name, kind+".yaml")
+			err := o.renderTemplate(o.CrossplaneTemplateDir, kind+".yaml.gotmpl", path, templateData)
+			if err != nil {
+				return errors.Wrapf(err, "failed to generate Crossplane %s claim", claim.Kind)
+			}
+		}
+	}
+
+	if o.SpinnakerTemplateDir != "" && repo.Spinnaker != nil {
+		err := o.writeSpinnakerPipeline(group, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.KeptnTemplateDir != "" && repo.Keptn != nil {
+		project := repo.Keptn.Project
+		if project == "" {
+			project = group.Owner
+		}
+		stage := repo.Keptn.Stage
+		if stage == "" {
+			stage = DefaultKeptnStage
+		}
+		service := repo.Keptn.Service
+		if service == "" {
+			service = repo.Name
+		}
+		templateData := o.createTemplateData(group, repo)
+		templateData["KeptnProject"] = project
+		templateData["KeptnStage"] = stage
+		templateData["KeptnService"] = service
+		for _, name := range []string{"slo.yaml", "sli.yaml"} {
+			path := filepath.Join(o.OutDir, "keptn", project, stage, service, name)
+			err := o.renderTemplate(o.KeptnTemplateDir, name+".gotmpl", path, templateData)
+			if err != nil {
+				return errors.Wrapf(err, "failed to generate Keptn %s", name)
+			}
+		}
+	}
 
-		data, err := yaml.Marshal(values)
+	if o.TektonEventListenerTemplate != "" {
+		templateData := o.createTemplateData(group, repo)
+		templateData["TektonTriggerType"] = tektonTriggerType(group.ProviderKind)
+		path := filepath.Join(o.OutDir, "tekton", repo.Name+"-eventlistener.yaml")
+		err := o.renderTemplate(filepath.Dir(o.TektonEventListenerTemplate), filepath.Base(o.TektonEventListenerTemplate), path, templateData)
 		if err != nil {
-			return errors.Wrapf(err, "failed to marshal values YAML for server %s", server)
+			return errors.Wrapf(err, "failed to generate Tekton EventListener")
 		}
+	}
 
-		err = ioutil.WriteFile(path, data, files.DefaultFileWritePermissions)
+	if o.BackstageTemplate != "" {
+		path := filepath.Join(o.OutDir, "backstage", group.Owner, repo.Name, "catalog-info.yaml")
+		err := o.renderTemplate(filepath.Dir(o.BackstageTemplate), filepath.Base(o.BackstageTemplate), path, o.createTemplateData(group, repo))
 		if err != nil {
-			return errors.Wrapf(err, "failed to save file %s", path)
+			return errors.Wrapf(err, "failed to generate Backstage Component")
 		}
 	}
 
+	if o.GitHubRepoSettingsTemplate != "" && group.ProviderKind == "github" {
+		path := filepath.Join(o.OutDir, "github-settings", group.Owner, repo.Name, ".github", "settings.yml")
+		err := o.renderTemplate(filepath.Dir(o.GitHubRepoSettingsTemplate), filepath.Base(o.GitHubRepoSettingsTemplate), path, o.createTemplateData(group, repo))
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate GitHub repository settings")
+		}
+	}
 	return nil
 }
 
+// writeRenovateConfig renders the Renovate configuration for the repositories of the group
+func (o *Options) writeRenovateConfig(group *v1alpha1.RepositoryGroup) error {
+	var repositories []string
+	for i := range group.Repositories {
+		repositories = append(repositories, group.Repositories[i].Name)
+	}
+	templateData := map[string]interface{}{
+		"Owner":             group.Owner,
+		"GitServerURL":      group.Provider,
+		"GitKind":           group.ProviderKind,
+		"GitName":           group.ProviderName,
+		"Language":          group.Language,
+		"DependencyManager": group.DependencyManager,
+		"Repositories":      repositories,
+	}
+	path := filepath.Join(o.OutDir, "renovate", group.Owner, "renovate.json")
+	return o.renderTemplate(filepath.Dir(o.RenovateTemplate), filepath.Base(o.RenovateTemplate), path, templateData)
+}
+
+// gitLabProjectID looks up the ID of the GitLab project of the repository using a client for each GitLab server
+func (o *Options) gitLabProjectID(group *v1alpha1.RepositoryGroup, repo *v1alpha1.Repository) (string, error) {
+	if o.GitLabClients == nil {
+		o.GitLabClients = map[string]*scm.Client{}
+	}
+	client := o.GitLabClients[group.Provider]
+	if client == nil {
+		var err error
+		client, err = factory.NewClient("gitlab", group.Provider, o.GitLabToken)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to create the GitLab client for %s", group.Provider)
+		}
+		o.GitLabClients[group.Provider] = client
+	}
+	fullName := scm.Join(group.Owner, repo.Name)
+	project, _, err := client.Repositories.Find(context.Background(), fullName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find GitLab project %s", fullName)
+	}
+	return project.ID, nil
+}
+
+// circleCIVCSType returns the CircleCI VCS type used in the organisation slug for the given git provider kind
+// or an empty string if CircleCI does not support the git provider
+func circleCIVCSType(gitKind string) string {
+	switch gitKind {
+	case "github":
+		return "github"
+	case "bitbucket", "bitbucketcloud":
+		return "bitbucket"
+	default:
+		return ""
+	}
+}
+
+// entityIdentifier returns a valid Harness or TeamCity identifier for the given name
+// by replacing any characters other than letters, digits and underscores
+func entityIdentifier(name string) string {
+	identifier := strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if identifier != "" && identifier[0] >= '0' && identifier[0] <= '9' {
+		identifier = "_" + identifier
+	}
+	return identifier
+}
+
+// isBitbucket returns true if the git provider kind is Bitbucket Cloud or Bitbucket Server
+func isBitbucket(gitKind string) bool {
+	return gitKind == "bitbucket" || gitKind == "bitbucketcloud" || gitKind == "bitbucketserver"
+}
+
+// bitbucketRepoSlug returns the Bitbucket repository slug for the given repository name
+func bitbucketRepoSlug(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "-")
+}
+
+// tektonTriggerType returns the Tekton Triggers interceptor used to handle the webhooks of the given git provider kind
+func tektonTriggerType(gitKind string) string {
+	switch gitKind {
+	case "github", "gitlab":
+		return gitKind
+	case "bitbucketserver", "bitbucketcloud":
+		return "bitbucket"
+	default:
+		return "cel"
+	}
+}
+
 func (o *Options) processJenkinsConfig(group *v1alpha1.RepositoryGroup, repo *v1alpha1.Repository, jc *v1alpha1.JenkinsConfig) error {
 	server := jc.Server
 	if server == "" {
 		log.Logger().Infof("ignoring repository %s as it has no Jenkins server defined", repo.URL)
 		return nil
 	}
-	xmlTemplate := o.DefaultXmlTemplate
-	if jc.XmlTemplate != "" {
-		xmlTemplate = filepath.Join(o.Dir, jc.XmlTemplate)
+	xmlTemplate := o.xmlTemplatePath(jc)
+	if jc.XmlTemplate != "" && !isS3URL(xmlTemplate) {
 		exists, err := files.FileExists(xmlTemplate)
 		if err != nil {
 			return errors.Wrapf(err, "failed to check if file exists %s", xmlTemplate)
@@ -200,12 +844,475 @@ func (o *Options) processJenkinsConfig(group *v1alpha1.RepositoryGroup, repo *v1
 		return nil
 	}
 
-	data, err := ioutil.ReadFile(xmlTemplate)
+	text, err := o.loadXMLTemplate(xmlTemplate)
 	if err != nil {
-		return errors.Wrapf(err, "failed to load file %s", xmlTemplate)
+		return errors.Wrapf(err, "failed to load XML template %s", xmlTemplate)
 	}
 
-	templateData := map[string]interface{}{
+	templateData := o.createTemplateData(group, repo)
+	templateData["Slack"] = nil
+	if jc.SlackNotification != nil {
+		templateData["Slack"] = map[string]interface{}{
+			"Channel":    jc.SlackNotification.Channel,
+			"OnSuccess":  jc.SlackNotification.OnSuccess,
+			"OnFailure":  jc.SlackNotification.OnFailure,
+			"OnUnstable": jc.SlackNotification.OnUnstable,
+		}
+	}
+	templateData["BuildTimeoutMinutes"] = 0
+	if jc.BuildTimeout != nil {
+		templateData["BuildTimeoutMinutes"] = int(math.Ceil(jc.BuildTimeout.Duration.Minutes()))
+	}
+	templateData["Sonar"] = nil
+	if jc.SonarQube != nil {
+		projectKey := jc.SonarQube.ProjectKey
+		if projectKey == "" {
+			projectKey = sonarKey(group.Owner, repo.Name)
+		}
+		templateData["Sonar"] = map[string]interface{}{
+			"ProjectKey":  projectKey,
+			"ServerURL":   jc.SonarQube.ServerURL,
+			"QualityGate": jc.SonarQube.QualityGate,
+		}
+	}
+	templateData["NexusIQ"] = nil
+	var nexusIQ *v1alpha1.NexusConfig
+	if jc.NexusIQ != nil {
+		nexusIQ = &v1alpha1.NexusConfig{
+			ApplicationID: jc.NexusIQ.ApplicationID,
+			Stage:         jc.NexusIQ.Stage,
+		}
+		if nexusIQ.ApplicationID == "" {
+			nexusIQ.ApplicationID = group.Owner + "-" + repo.Name
+		}
+		if nexusIQ.Stage == "" {
+			nexusIQ.Stage = DefaultNexusIQStage
+		}
+		templateData["NexusIQ"] = map[string]interface{}{
+			"ApplicationID": nexusIQ.ApplicationID,
+			"Stage":         nexusIQ.Stage,
+		}
+	}
+
+	var keda *v1alpha1.KEDAConfig
+	if jc.KEDA != nil {
+		keda = &v1alpha1.KEDAConfig{
+			MinReplicas: jc.KEDA.MinReplicas,
+			MaxReplicas: jc.KEDA.MaxReplicas,
+			QueueLabel:  jc.KEDA.QueueLabel,
+		}
+		if keda.MaxReplicas == 0 {
+			keda.MaxReplicas = DefaultScaledJobMaxReplicas
+		}
+		if keda.QueueLabel == "" {
+			keda.QueueLabel = repo.Name
+		}
+	}
+
+	o.JenkinsServers[server] = append(o.JenkinsServers[server], &JenkinsTemplateConfig{
+		Server:          server,
+		Key:             repo.Name,
+		XMLTemplateFile: xmlTemplate,
+		XMLTemplateText: text,
+		TemplateData:    templateData,
+		SharedLibraries: jc.SharedLibraries,
+		GlobalLibraries: jc.GlobalLibraries,
+		NexusIQ:         nexusIQ,
+		KEDA:            keda,
+	})
+	return nil
+}
+
+// writeValues writes the helm values.yaml file for the Jenkins server
+func (o *Options) writeValues(dir, server string, configs []*JenkinsTemplateConfig, jobs map[string]interface{}) error {
+	path := filepath.Join(dir, o.valuesFileName())
+	log.Logger().Infof("creating Jenkins values file %s", path)
+
+	master := map[string]interface{}{
+		"jobs": jobs,
+	}
+	values := map[string]interface{}{
+		o.valuesKey: master,
+	}
+
+	addSharedLibraryValues(master, uniqueLibraries(configs, func(jcfg *JenkinsTemplateConfig) []v1alpha1.SharedLibraryConfig {
+		return jcfg.SharedLibraries
+	}))
+
+	libs := uniqueLibraries(configs, func(jcfg *JenkinsTemplateConfig) []v1alpha1.SharedLibraryConfig {
+		return jcfg.GlobalLibraries
+	})
+	if len(libs) > 0 {
+		master["globalLibraries"] = libs
+	}
+
+	if o.Merge {
+		var err error
+		existingPath := path
+		if o.mergeDir != "" {
+			rel, err := filepath.Rel(o.OutDir, path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to find relative path of %s", path)
+			}
+			existingPath = filepath.Join(o.mergeDir, rel)
+		}
+		values, err = mergeExistingValues(existingPath, values)
+		if err != nil {
+			return errors.Wrapf(err, "failed to merge with existing values for server %s", server)
+		}
+	}
+
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal values YAML for server %s", server)
+	}
+
+	if o.EmitDiffs {
+		err = o.writeDiff(dir, path, string(data))
+		if err != nil {
+			return errors.Wrapf(err, "failed to write diff for server %s", server)
+		}
+	}
+
+	err = writeFile(path, data)
+	if err != nil {
+		return err
+	}
+
+	if o.Env != "" {
+		err = o.writeEnvValues(dir, data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write %s environment values for server %s", o.Env, server)
+		}
+	}
+	return nil
+}
+
+// jenkinsServerConfig returns the configuration of the given Jenkins server if there is one
+func (o *Options) jenkinsServerConfig(server string) *v1alpha1.JenkinsServerConfig {
+	for i := range o.SourceConfig.Spec.JenkinsServers {
+		sc := &o.SourceConfig.Spec.JenkinsServers[i]
+		if sc.Server == server {
+			return sc
+		}
+	}
+	return nil
+}
+
+// writeCredentials writes the credentials.yaml values file for the credentials required by a server
+func writeCredentials(dir, valuesKey string, credentials []v1alpha1.CredentialConfig) error {
+	var entries []interface{}
+	for _, c := range credentials {
+		kind := c.Kind
+		if kind == "" {
+			kind = "usernamePassword"
+		}
+		entry := map[string]interface{}{
+			"scope": "GLOBAL",
+			"id":    c.ID,
+		}
+		if c.Description != "" {
+			entry["description"] = c.Description
+		}
+		entries = append(entries, map[string]interface{}{
+			kind: entry,
+		})
+	}
+
+	values := map[string]interface{}{
+		valuesKey: map[string]interface{}{
+			"credentials": map[string]interface{}{
+				"system": map[string]interface{}{
+					"domainCredentials": []interface{}{
+						map[string]interface{}{
+							"credentials": entries,
+						},
+					},
+				},
+			},
+		},
+	}
+	path := filepath.Join(dir, "credentials.yaml")
+	err := yamls.SaveFile(values, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
+
+// writeNexusIQApps writes the Nexus IQ applications of the jobs of the server if there are any
+func writeNexusIQApps(dir string, configs []*JenkinsTemplateConfig) error {
+	var apps []interface{}
+	for _, c := range configs {
+		if c.NexusIQ == nil {
+			continue
+		}
+		apps = append(apps, map[string]interface{}{
+			"applicationId": c.NexusIQ.ApplicationID,
+			"stage":         c.NexusIQ.Stage,
+			"job":           c.Key,
+		})
+	}
+	if len(apps) == 0 {
+		return nil
+	}
+	values := map[string]interface{}{
+		"applications": apps,
+	}
+	path := filepath.Join(dir, NexusIQAppsFile)
+	err := yamls.SaveFile(values, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
+
+// mergeExistingValues merges the generated values into the existing values file if it exists
+func mergeExistingValues(path string, values map[string]interface{}) (map[string]interface{}, error) {
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return values, nil
+	}
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &existing)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse file %s", path)
+	}
+	mergeValues(existing, values)
+	return existing, nil
+}
+
+// mergeValues deeply merges the source values into the destination replacing the jobs map entirely
+func mergeValues(dest, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, ok := v.(map[string]interface{})
+		if ok && k != "jobs" {
+			destMap, ok := dest[k].(map[string]interface{})
+			if ok {
+				mergeValues(destMap, srcMap)
+				continue
+			}
+		}
+		dest[k] = v
+	}
+}
+
+// addSharedLibraryValues adds the global node properties and existing secrets used to configure the shared libraries
+func addSharedLibraryValues(master map[string]interface{}, libraries []v1alpha1.SharedLibraryConfig) {
+	if len(libraries) == 0 {
+		return
+	}
+	var env []interface{}
+	var secrets []interface{}
+	secretNames := map[string]bool{}
+	for _, lib := range libraries {
+		prefix := "LIBRARY_" + libraryEnvVarName(lib.Name)
+		env = append(env, envVarValue(prefix+"_URL", lib.URL))
+		if lib.DefaultVersion != "" {
+			env = append(env, envVarValue(prefix+"_DEFAULT_VERSION", lib.DefaultVersion))
+		}
+		env = append(env, envVarValue(prefix+"_IMPLICIT", strconv.FormatBool(lib.Implicit)))
+
+		// mount the credentials secret so that Configuration as Code can refer to it
+		if lib.CredentialID != "" && !secretNames[lib.CredentialID] {
+			secretNames[lib.CredentialID] = true
+			for _, key := range []string{"username", "password"} {
+				secrets = append(secrets, map[string]interface{}{
+					"name":    lib.CredentialID,
+					"keyName": key,
+				})
+			}
+		}
+	}
+	master["globalNodeProperties"] = []interface{}{
+		map[string]interface{}{
+			"envVars": map[string]interface{}{
+				"env": env,
+			},
+		},
+	}
+	if len(secrets) > 0 {
+		master["additionalExistingSecrets"] = secrets
+	}
+}
+
+func envVarValue(key, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"key":   key,
+		"value": value,
+	}
+}
+
+func libraryEnvVarName(name string) string {
+	text := strings.ToUpper(name)
+	text = strings.ReplaceAll(text, ".", "_")
+	text = strings.ReplaceAll(text, "-", "_")
+	return text
+}
+
+// uniqueLibraries returns the libraries used by the jobs removing any duplicate names
+func uniqueLibraries(configs []*JenkinsTemplateConfig, fn func(*JenkinsTemplateConfig) []v1alpha1.SharedLibraryConfig) []v1alpha1.SharedLibraryConfig {
+	var answer []v1alpha1.SharedLibraryConfig
+	names := map[string]bool{}
+	for _, jcfg := range configs {
+		for _, lib := range fn(jcfg) {
+			if names[lib.Name] {
+				continue
+			}
+			names[lib.Name] = true
+			answer = append(answer, lib)
+		}
+	}
+	return answer
+}
+
+// writeConfigMap writes a ConfigMap containing the job XML configurations for the server
+func (o *Options) writeConfigMap(dir, server string, jobsXML map[string]string) error {
+	name := server + "-jobs"
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: o.labels,
+		},
+		Data: jobsXML,
+	}
+	path := filepath.Join(dir, name+"-cm.yaml")
+	err := yamls.SaveFile(cm, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
+
+// writeChart writes the Chart.yaml file into the given server dir
+func (o *Options) writeChart(dir string) error {
+	chart := &ChartMetadata{
+		APIVersion:  "v2",
+		Name:        o.ChartName,
+		Version:     o.ChartVersion,
+		Description: o.ChartDescription,
+	}
+	path := filepath.Join(dir, "Chart.yaml")
+	err := yamls.SaveFile(chart, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
+
+// xmlTemplatePath returns the XML template file for the given configuration or the default XML template
+func (o *Options) xmlTemplatePath(jc *v1alpha1.JenkinsConfig) string {
+	if isS3URL(jc.XmlTemplate) {
+		return jc.XmlTemplate
+	}
+	if jc.XmlTemplate != "" {
+		return filepath.Join(o.Dir, jc.XmlTemplate)
+	}
+	return o.DefaultXmlTemplate
+}
+
+// loadXMLTemplate loads the XML template from the local file system or from S3
+func (o *Options) loadXMLTemplate(path string) (string, error) {
+	if isS3URL(path) {
+		return o.loadS3Template(path)
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to stat file %s", path)
+	}
+	err = o.checkTemplateSize(path, fileInfo.Size())
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load file %s", path)
+	}
+	return string(data), nil
+}
+
+// checkTemplateSize returns an error if the template is larger than the --max-template-size
+func (o *Options) checkTemplateSize(path string, size int64) error {
+	if o.MaxTemplateSize > 0 && size > o.MaxTemplateSize {
+		return errors.Errorf("the template %s is %d bytes which exceeds the --max-template-size of %d bytes", path, size, o.MaxTemplateSize)
+	}
+	return nil
+}
+
+// loadCredentialsConfigMap loads the credentials ConfigMap once so its entries can be used in the templates
+func (o *Options) loadCredentialsConfigMap() error {
+	if o.credentialValues != nil {
+		return nil
+	}
+	o.credentialValues = map[string]string{}
+	if o.CredentialsConfigMap == "" {
+		return nil
+	}
+	values := strings.SplitN(o.CredentialsConfigMap, "/", 2)
+	if len(values) != 2 || values[0] == "" || values[1] == "" {
+		return options.InvalidOptionf("credentials-configmap", o.CredentialsConfigMap, "should be of the form namespace/name")
+	}
+	ns, name := values[0], values[1]
+
+	var err error
+	o.KubeClient, err = kube.LazyCreateKubeClient(o.KubeClient)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create kube client")
+	}
+	cm, err := o.KubeClient.CoreV1().ConfigMaps(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to load ConfigMap %s in namespace %s", name, ns)
+	}
+	for k, v := range cm.Data {
+		o.credentialValues[k] = v
+	}
+	return nil
+}
+
+// templateFuncMap returns the functions available in the templates
+func (o *Options) templateFuncMap() map[string]interface{} {
+	funcMap := sprig.TxtFuncMap()
+	funcMap["sonarKey"] = sonarKey
+	funcMap["include"] = o.includeTemplate
+	funcMap["credentials"] = func() []v1alpha1.CredentialConfig {
+		return o.serverCredentials
+	}
+	return funcMap
+}
+
+// includeTemplate renders the partial template file relative to the directory with the given data
+func (o *Options) includeTemplate(name string, data interface{}) (string, error) {
+	path := filepath.Join(o.Dir, name)
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load partial template %s", path)
+	}
+	templateData, ok := data.(map[string]interface{})
+	if !ok {
+		return "", errors.Errorf("the data passed to the partial template %s should be the template data", path)
+	}
+	return templater.Evaluate(o.templateFuncMap(), templateData, string(text), path, "partial "+name)
+}
+
+// sonarKey derives the SonarQube project key of a repository
+func sonarKey(owner, repository string) string {
+	return owner + ":" + repository
+}
+
+// createTemplateData creates the data used to render the templates for a repository
+func (o *Options) createTemplateData(group *v1alpha1.RepositoryGroup, repo *v1alpha1.Repository) map[string]interface{} {
+	return map[string]interface{}{
 		"Owner":        group.Owner,
 		"GitServerURL": group.Provider,
 		"GitKind":      group.ProviderKind,
@@ -213,14 +1320,93 @@ func (o *Options) processJenkinsConfig(group *v1alpha1.RepositoryGroup, repo *v1
 		"Repository":   repo.Name,
 		"URL":          repo.URL,
 		"CloneURL":     repo.HTTPCloneURL,
+		"Credentials":  o.credentialValues,
 	}
+}
 
-	o.JenkinsServers[server] = append(o.JenkinsServers[server], &JenkinsTemplateConfig{
-		Server:          server,
-		Key:             repo.Name,
-		XMLTemplateFile: xmlTemplate,
-		XMLTemplateText: string(data),
-		TemplateData:    templateData,
-	})
+// renderTemplate renders the named template file in the template dir to the given output path
+func (o *Options) renderTemplate(templateDir, templateName, path string, templateData map[string]interface{}) error {
+	templateFile := filepath.Join(templateDir, templateName)
+	data, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load template file %s", templateFile)
+	}
+
+	o.logTemplateData(templateFile, templateData)
+	output, err := templater.Evaluate(o.templateFuncMap(), templateData, string(data), templateFile, "file "+path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to evaluate template %s", templateFile)
+	}
+
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	err = ioutil.WriteFile(path, []byte(output), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	log.Logger().Infof("created file %s", info(path))
+	return nil
+}
+
+// diffLines returns the line based differences between the old and new text prefixing inserted lines with + and deleted lines with -
+func diffLines(oldText, newText string) string {
+	dmp := diffmatchpatch.New()
+	oldChars, newChars, lines := dmp.DiffLinesToChars(oldText, newText)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(oldChars, newChars, false), lines)
+
+	buf := strings.Builder{}
+	for _, d := range diffs {
+		prefix := " "
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line == "" {
+				continue
+			}
+			buf.WriteString(prefix + line)
+			if !strings.HasSuffix(line, "\n") {
+				buf.WriteString("\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+// writeDiff writes a changes.diff file into the given dir if the new contents differ from the current file contents
+// otherwise any changes.diff file from a previous run is removed
+func (o *Options) writeDiff(dir, path, newText string) error {
+	oldText := ""
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if exists {
+		data, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		oldText = string(data)
+	}
+
+	diffFile := filepath.Join(dir, "changes.diff")
+	if oldText == newText {
+		err = os.Remove(diffFile)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove file %s", diffFile)
+		}
+		return nil
+	}
+	err = ioutil.WriteFile(diffFile, []byte(diffLines(oldText, newText)), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", diffFile)
+	}
+	log.Logger().Infof("created diff file %s", info(diffFile))
 	return nil
 }
@@ -1,13 +1,31 @@
 package jobs_test
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/h2non/gock"
+	"github.com/jenkins-x/go-scm/scm"
+	scmfake "github.com/jenkins-x/go-scm/scm/driver/fake"
 	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/httphelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/maps"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
 )
 
 func TestJenkinsJobs(t *testing.T) {
@@ -26,4 +44,1011 @@ func TestJenkinsJobs(t *testing.T) {
 	expectedFile := filepath.Join(tmpDir, "myjenkins", "values.yaml")
 	assert.FileExists(t, expectedFile, "should have generated file")
 	t.Logf("generated %s\n", expectedFile)
+
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "<room>#myapp-builds</room>", "slack notification in %s", expectedFile)
+	assert.Contains(t, string(data), "SONAR_PROJECT_KEY=myorg:another", "sonar project key in %s", expectedFile)
+	assert.Contains(t, string(data), "<timeoutMinutes>90</timeoutMinutes>", "build timeout in %s", expectedFile)
+	assert.Equal(t, 1, strings.Count(string(data), "BuildTimeoutWrapper plugin="), "only one job should have a build timeout in %s", expectedFile)
+	assert.Equal(t, 1, strings.Count(string(data), "SlackNotifier plugin="), "only one job should notify slack in %s", expectedFile)
+	assert.Contains(t, string(data), "<credentialsId>git-credentials</credentialsId>", "credentials rendered by a partial template in %s", expectedFile)
+
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &values)
+	require.NoError(t, err, "failed to parse file %s", expectedFile)
+	libraries, _, err := unstructured.NestedSlice(values, "master", "globalLibraries")
+	require.NoError(t, err, "failed to get the global libraries of %s", expectedFile)
+	expectedLibraries := []interface{}{
+		map[string]interface{}{
+			"name":           "release-library",
+			"url":            "https://github.com/myorg/release-library.git",
+			"defaultVersion": "v1.2.0",
+			"credentialId":   "git-credentials",
+		},
+	}
+	assert.Equal(t, expectedLibraries, libraries, "global libraries in %s", expectedFile)
+
+	nodeProperties, _, err := unstructured.NestedSlice(values, "master", "globalNodeProperties")
+	require.NoError(t, err, "failed to get the global node properties of %s", expectedFile)
+	expectedNodeProperties := []interface{}{
+		map[string]interface{}{
+			"envVars": map[string]interface{}{
+				"env": []interface{}{
+					map[string]interface{}{"key": "LIBRARY_PIPELINE_LIBRARY_URL", "value": "https://github.com/myorg/pipeline-library.git"},
+					map[string]interface{}{"key": "LIBRARY_PIPELINE_LIBRARY_DEFAULT_VERSION", "value": "main"},
+					map[string]interface{}{"key": "LIBRARY_PIPELINE_LIBRARY_IMPLICIT", "value": "false"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expectedNodeProperties, nodeProperties, "global node properties in %s", expectedFile)
+
+	secrets, _, err := unstructured.NestedSlice(values, "master", "additionalExistingSecrets")
+	require.NoError(t, err, "failed to get the additional existing secrets of %s", expectedFile)
+	expectedSecrets := []interface{}{
+		map[string]interface{}{"name": "library-credentials", "keyName": "username"},
+		map[string]interface{}{"name": "library-credentials", "keyName": "password"},
+	}
+	assert.Equal(t, expectedSecrets, secrets, "additional existing secrets in %s", expectedFile)
+
+	credentialsFile := filepath.Join(tmpDir, "myjenkins", "credentials.yaml")
+	data, err = ioutil.ReadFile(credentialsFile)
+	require.NoError(t, err, "failed to load file %s", credentialsFile)
+	assert.Contains(t, string(data), "id: git-credentials", "credentials file %s", credentialsFile)
+}
+
+func TestJenkinsJobsEmitDiffs(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.EmitDiffs = true
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	diffFile := filepath.Join(tmpDir, "myjenkins", "changes.diff")
+	require.FileExists(t, diffFile, "should have generated a diff file")
+	data, err := ioutil.ReadFile(diffFile)
+	require.NoError(t, err, "failed to load file %s", diffFile)
+	assert.Contains(t, string(data), "+master:", "diff file %s", diffFile)
+
+	// lets regenerate without any changes
+	_, o = jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.EmitDiffs = true
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command again in dir %s", tmpDir)
+	assert.NoFileExists(t, diffFile, "should have removed the diff file as nothing changed")
+}
+
+func TestJenkinsJobsNexusIQ(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "myjenkins", "values.yaml")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "<applicationId>myorg-another</applicationId>", "nexus application in %s", expectedFile)
+	assert.Contains(t, string(data), "<iqStage>release</iqStage>", "nexus stage in %s", expectedFile)
+	assert.Equal(t, 1, strings.Count(string(data), "IqPolicyEvaluatorBuildStep plugin="), "only one job should evaluate policies in %s", expectedFile)
+
+	appsFile := filepath.Join(tmpDir, "myjenkins", jobs.NexusIQAppsFile)
+	apps := map[string]interface{}{}
+	err = yamls.LoadFile(appsFile, &apps)
+	require.NoError(t, err, "failed to load file %s", appsFile)
+	expected := map[string]interface{}{
+		"applications": []interface{}{
+			map[string]interface{}{
+				"applicationId": "myorg-another",
+				"stage":         "release",
+				"job":           "another",
+			},
+		},
+	}
+	assert.Equal(t, expected, apps, "Nexus IQ applications in %s", appsFile)
+}
+
+func TestJenkinsJobsWoodpecker(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.WoodpeckerTemplateDir = filepath.Join("test_data", "ci", "woodpecker")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "woodpecker", "myorg", "myapp", ".woodpecker.yaml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "remote: https://github.com/myorg/myapp.git", "generated file %s", expectedFile)
+}
+
+func TestJenkinsJobsAzureDevOps(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ConfigFile = filepath.Join("test_data", "azure", "source-config.yaml")
+	o.AzureDevOpsTemplateDir = filepath.Join("test_data", "ci", "azuredevops")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "azuredevops", "myproject", "myapp", "azure-pipelines.yml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "name: myproject/myapp", "generated file %s", expectedFile)
+}
+
+func TestJenkinsJobsGitLabCI(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	scmClient, fakeData := scmfake.NewDefault()
+	fakeData.Repositories = append(fakeData.Repositories, &scm.Repository{
+		ID:        "1234",
+		Namespace: "mygroup",
+		Name:      "myapp",
+		FullName:  "mygroup/myapp",
+	})
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ConfigFile = filepath.Join("test_data", "gitlab", "source-config.yaml")
+	o.GitLabCITemplateDir = filepath.Join("test_data", "ci", "gitlab")
+	o.GitLabClients = map[string]*scm.Client{
+		"https://gitlab.com": scmClient,
+	}
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "gitlab-ci", "mygroup", "myapp", ".gitlab-ci.yml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), `PROJECT_ID: "1234"`, "generated file %s", expectedFile)
+	assert.Contains(t, string(data), "REPOSITORY: mygroup/myapp", "generated file %s", expectedFile)
+}
+
+func TestJenkinsJobsGitLabCIMultipleServers(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	clients := map[string]*scm.Client{}
+	for provider, id := range map[string]string{"https://gitlab.com": "1234", "https://gitlab.example.com": "5678"} {
+		scmClient, fakeData := scmfake.NewDefault()
+		for _, name := range []string{"myapp", "another"} {
+			fakeData.Repositories = append(fakeData.Repositories, &scm.Repository{
+				ID:        id,
+				Namespace: "mygroup",
+				Name:      name,
+				FullName:  "mygroup/" + name,
+			})
+		}
+		clients[provider] = scmClient
+	}
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ConfigFile = filepath.Join("test_data", "gitlab-servers", "source-config.yaml")
+	o.GitLabCITemplateDir = filepath.Join("test_data", "ci", "gitlab")
+	o.GitLabClients = clients
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	for name, id := range map[string]string{"myapp": "1234", "another": "5678"} {
+		expectedFile := filepath.Join(tmpDir, "gitlab-ci", "mygroup", name, ".gitlab-ci.yml")
+		data, err := ioutil.ReadFile(expectedFile)
+		require.NoError(t, err, "failed to load file %s", expectedFile)
+		assert.Contains(t, string(data), `PROJECT_ID: "`+id+`"`, "generated file %s", expectedFile)
+	}
+}
+
+func TestJenkinsJobsGitLabTokenFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	tokenFile := filepath.Join(tmpDir, "token")
+	err = ioutil.WriteFile(tokenFile, []byte("mytoken\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", tokenFile)
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.GitLabTokenFile = tokenFile
+
+	err = o.Validate()
+	require.NoError(t, err, "failed to validate options")
+	assert.Equal(t, "mytoken", o.GitLabToken, "GitLab token")
+}
+
+func TestJenkinsJobsBitbucketPipelines(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ConfigFile = filepath.Join("test_data", "bitbucket", "source-config.yaml")
+	o.BitbucketPipelinesDir = filepath.Join("test_data", "ci", "bitbucket")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "bitbucket-pipelines", "myworkspace", "MyApp", "bitbucket-pipelines.yml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "# repository: myworkspace/myapp", "generated file %s", expectedFile)
+}
+
+func TestJenkinsJobsSpecDefaults(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ConfigFile = filepath.Join("test_data", "defaults", "source-config.yaml")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "myjenkins", "values.yaml")
+	require.FileExists(t, expectedFile, "should have generated file using the default jenkins server")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "https://github.example.com/myorg/myapp", "generated file %s", expectedFile)
+
+	expectedFile = filepath.Join(tmpDir, "otherjenkins", "values.yaml")
+	require.FileExists(t, expectedFile, "should have generated file using the group jenkins server")
+	data, err = ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "https://github.com/otherorg/other", "generated file %s", expectedFile)
+}
+
+func TestJenkinsJobsTemplateLint(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.TemplateLint = true
+	o.LintStrict = true
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+	assert.FileExists(t, filepath.Join(tmpDir, "myjenkins", "values.yaml"), "should have generated file")
+
+	templateFile := filepath.Join(tmpDir, "env.xml.gotmpl")
+	err = ioutil.WriteFile(templateFile, []byte(`<project>{{ env "HOME" }}</project>`), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", templateFile)
+	configFile := filepath.Join(tmpDir, "source-config.yaml")
+	config := `apiVersion: gitops.jenkins-x.io/v1alpha1
+kind: SourceConfig
+spec:
+  groups:
+  - owner: myorg
+    jenkins:
+      server: myjenkins
+    repositories:
+    - name: myapp
+`
+	err = ioutil.WriteFile(configFile, []byte(config), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", configFile)
+
+	_, o = jobs.NewCmdJenkinsJobs()
+	o.OutDir = filepath.Join(tmpDir, "output")
+	o.Dir = "test_data"
+	o.ConfigFile = configFile
+	o.DefaultXmlTemplate = templateFile
+	o.TemplateLint = true
+
+	err = o.Run()
+	require.NoError(t, err, "lint issues should only be warnings")
+
+	o.LintStrict = true
+	err = o.Run()
+	require.Error(t, err, "lint issues should fail with --lint-strict")
+	assert.Contains(t, err.Error(), "dangerous function", "error")
+}
+
+func TestJenkinsJobsFluxKustomization(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.FluxKustomizationTemplate = filepath.Join("test_data", "flux", "kustomization.yaml.gotmpl")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	path := filepath.Join(tmpDir, "flux", "myjenkins-kustomization.yaml")
+	kustomization := map[string]interface{}{}
+	err = yamls.LoadFile(path, &kustomization)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Equal(t, filepath.Join(tmpDir, "myjenkins"), maps.GetMapValueAsStringViaPath(kustomization, "spec.path"), "path in %s", path)
+	assert.NotNil(t, maps.GetMapValueViaPath(kustomization, "spec.healthChecks"), "health checks in %s", path)
+}
+
+func TestJenkinsJobsTektonEventListener(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.TektonEventListenerTemplate = filepath.Join("test_data", "tekton", "eventlistener.yaml.gotmpl")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	for _, name := range []string{"myapp", "another"} {
+		path := filepath.Join(tmpDir, "tekton", name+"-eventlistener.yaml")
+		listener := map[string]interface{}{}
+		err = yamls.LoadFile(path, &listener)
+		require.NoError(t, err, "failed to load file %s", path)
+		assert.Equal(t, "EventListener", listener["kind"], "kind in %s", path)
+		assert.Equal(t, name, maps.GetMapValueAsStringViaPath(listener, "metadata.name"), "name in %s", path)
+
+		triggers, _, err := unstructured.NestedSlice(listener, "spec", "triggers")
+		require.NoError(t, err, "failed to get triggers of %s", path)
+		require.Len(t, triggers, 1, "triggers in %s", path)
+		interceptors, _, err := unstructured.NestedSlice(triggers[0].(map[string]interface{}), "interceptors")
+		require.NoError(t, err, "failed to get interceptors of %s", path)
+		require.Len(t, interceptors, 1, "interceptors in %s", path)
+		assert.Equal(t, "github", maps.GetMapValueAsStringViaPath(interceptors[0].(map[string]interface{}), "ref.name"), "interceptor in %s", path)
+	}
+}
+
+func TestJenkinsJobsBackstage(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.BackstageTemplate = filepath.Join("test_data", "backstage", "component.yaml.gotmpl")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	path := filepath.Join(tmpDir, "backstage", "myorg", "myapp", "catalog-info.yaml")
+	component := map[string]interface{}{}
+	err = yamls.LoadFile(path, &component)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Equal(t, "Component", component["kind"], "kind in %s", path)
+	assert.Equal(t, "myapp", maps.GetMapValueAsStringViaPath(component, "metadata.name"), "name in %s", path)
+	assert.Equal(t, "myorg", maps.GetMapValueAsStringViaPath(component, "spec.owner"), "owner in %s", path)
+	annotations, _, err := unstructured.NestedStringMap(component, "metadata", "annotations")
+	require.NoError(t, err, "failed to get annotations of %s", path)
+	assert.Equal(t, "myorg/myapp", annotations["github.com/project-slug"], "project slug in %s", path)
+	assert.FileExists(t, filepath.Join(tmpDir, "backstage", "myorg", "another", "catalog-info.yaml"))
+}
+
+func TestJenkinsJobsGitHubRepoSettings(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.GitHubRepoSettingsTemplate = filepath.Join("test_data", "github", "settings.yml.gotmpl")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	for _, name := range []string{"myapp", "another"} {
+		path := filepath.Join(tmpDir, "github-settings", "myorg", name, ".github", "settings.yml")
+		settings := map[string]interface{}{}
+		err = yamls.LoadFile(path, &settings)
+		require.NoError(t, err, "failed to load file %s", path)
+		assert.Equal(t, name, maps.GetMapValueAsStringViaPath(settings, "repository.name"), "repository name in %s", path)
+	}
+}
+
+func TestJenkinsJobsRenovate(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.RenovateTemplate = filepath.Join("test_data", "renovate", "renovate.json.gotmpl")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	path := filepath.Join(tmpDir, "renovate", "myorg", "renovate.json")
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "failed to load file %s", path)
+	config := map[string]interface{}{}
+	err = json.Unmarshal(data, &config)
+	require.NoError(t, err, "failed to parse file %s", path)
+	assert.Equal(t, []interface{}{"myapp", "another"}, config["repositories"], "repositories in %s", path)
+	assert.Equal(t, []interface{}{"gomod"}, config["enabledManagers"], "enabled managers in %s", path)
+	assert.Equal(t, []interface{}{"dependencies", "go"}, config["labels"], "labels in %s", path)
+}
+
+func TestJenkinsJobsBuildkite(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.BuildkiteTemplateDir = filepath.Join("test_data", "ci", "buildkite")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "buildkite", "myorg", "another", ".buildkite", "pipeline.yml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "queue: linux", "generated file %s", expectedFile)
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "buildkite", "myorg", "myapp", ".buildkite", "pipeline.yml"), "should not generate a pipeline without buildkite configuration")
+}
+
+func TestJenkinsJobsSemaphore(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.SemaphoreTemplateDir = filepath.Join("test_data", "ci", "semaphore")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "semaphore", "myorg", "myapp", ".semaphore", "semaphore.yml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "name: myorg-ci-credentials", "generated file %s", expectedFile)
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "semaphore", "myorg", "another", ".semaphore", "semaphore.yml"), "should not generate a pipeline without semaphore configuration")
+}
+
+func TestJenkinsJobsCircleCI(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.CircleCITemplateDir = filepath.Join("test_data", "ci", "circleci")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "circleci", "myorg", "myapp", ".circleci", "config.yml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "# project: github/myorg/myapp", "generated file %s", expectedFile)
+}
+
+func TestJenkinsJobsHarness(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.HarnessTemplateDir = filepath.Join("test_data", "ci", "harness")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "harness", "platform", "another", "pipeline.yaml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "identifier: another", "generated file %s", expectedFile)
+	assert.Contains(t, string(data), "projectIdentifier: platform", "generated file %s", expectedFile)
+	assert.Contains(t, string(data), "orgIdentifier: default", "generated file %s", expectedFile)
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "harness", "myorg", "myapp", "pipeline.yaml"), "should not generate a pipeline without harness configuration")
+}
+
+func TestJenkinsJobsSpinnaker(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	client := httphelpers.GetClient()
+	gock.InterceptClient(client)
+
+	defer gock.Off()
+	defer gock.RestoreClient(client)
+
+	gock.New("https://gate.example.com").
+		Post("/pipelines").
+		MatchType("json").
+		BodyString(`"application": "platform"`).
+		Reply(200)
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.SpinnakerTemplateDir = filepath.Join("test_data", "ci", "spinnaker")
+	o.SpinnakerGateURL = "https://gate.example.com"
+	o.HTTPClient = client
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "spinnaker", "platform", "deploy.json")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	pipeline := map[string]interface{}{}
+	err = json.Unmarshal(data, &pipeline)
+	require.NoError(t, err, "failed to parse generated file %s", expectedFile)
+	assert.Equal(t, "platform", pipeline["application"], "application in generated file %s", expectedFile)
+	assert.Equal(t, "deploy", pipeline["name"], "name in generated file %s", expectedFile)
+
+	assert.NoDirExists(t, filepath.Join(tmpDir, "spinnaker", "myapp"), "should not generate a pipeline without spinnaker configuration")
+	assert.True(t, gock.IsDone(), "should have saved the pipeline via the Gate API")
+}
+
+func TestJenkinsJobsKeptn(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.KeptnTemplateDir = filepath.Join("test_data", "ci", "keptn")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	dir := filepath.Join(tmpDir, "keptn", "shop", jobs.DefaultKeptnStage, "myapp")
+	expectedFile := filepath.Join(dir, "slo.yaml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "service: myapp", "generated file %s", expectedFile)
+	assert.Contains(t, string(data), "stage: production", "generated file %s", expectedFile)
+
+	expectedFile = filepath.Join(dir, "sli.yaml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err = ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), `service="myapp"`, "generated file %s", expectedFile)
+
+	assert.NoDirExists(t, filepath.Join(tmpDir, "keptn", "myorg"), "should not generate quality gates without keptn configuration")
+}
+
+func TestJenkinsJobsKEDA(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.KEDATemplateDir = filepath.Join("test_data", "keda")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "keda", "myjenkins", jobs.ScaledJobsFile)
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	text := string(data)
+	assert.Equal(t, 1, strings.Count(text, "kind: ScaledJob"), "should only generate a ScaledJob for jobs with keda configuration in file %s", expectedFile)
+	assert.Contains(t, text, "name: myapp-agents", "generated file %s", expectedFile)
+	assert.Contains(t, text, "minReplicaCount: 1\n", "generated file %s", expectedFile)
+	assert.Contains(t, text, "maxReplicaCount: 10\n", "generated file %s", expectedFile)
+	assert.Contains(t, text, "/queue/api/json?label=myapp\n", "generated file %s", expectedFile)
+}
+
+func TestJenkinsJobsGrafana(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.GrafanaTemplateDir = filepath.Join("test_data", "grafana")
+	o.GrafanaDatasource = "Mimir"
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "grafana", "myjenkins", jobs.GrafanaDashboardFile)
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+
+	dashboard := map[string]interface{}{}
+	err = json.Unmarshal(data, &dashboard)
+	require.NoError(t, err, "failed to parse generated file %s", expectedFile)
+	assert.Equal(t, "Jenkins myjenkins", dashboard["title"], "title in generated file %s", expectedFile)
+	assert.Contains(t, string(data), `"datasource": "Mimir"`, "generated file %s", expectedFile)
+	assert.Contains(t, string(data), `"value": "myapp"`, "generated file %s", expectedFile)
+	assert.Contains(t, string(data), `"value": "another"`, "generated file %s", expectedFile)
+}
+
+func TestJenkinsJobsTeamCity(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.TeamCityTemplateDir = filepath.Join("test_data", "ci", "teamcity")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "teamcity", "MyOrg", "myapp", ".teamcity", "settings.kts")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), `id("MyOrg")`, "generated file %s", expectedFile)
+	assert.Contains(t, string(data), `id("MyOrg_myapp")`, "generated file %s", expectedFile)
+
+	assert.NoDirExists(t, filepath.Join(tmpDir, "teamcity", "MyOrg", "another"), "should not generate a build configuration without teamcity configuration")
+}
+
+func TestJenkinsJobsCrossplane(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.CrossplaneTemplateDir = filepath.Join("test_data", "crossplane")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	expectedFile := filepath.Join(tmpDir, "crossplane", "myapp", "postgresqlinstance.yaml")
+	require.FileExists(t, expectedFile, "should have generated file")
+	data, err := ioutil.ReadFile(expectedFile)
+	require.NoError(t, err, "failed to load file %s", expectedFile)
+	assert.Contains(t, string(data), "kind: PostgreSQLInstance", "generated file %s", expectedFile)
+	assert.Contains(t, string(data), "name: myapp-postgresqlinstance\n", "generated file %s", expectedFile)
+	assert.Contains(t, string(data), "storageGB: 20", "generated file %s", expectedFile)
+
+	assert.NoDirExists(t, filepath.Join(tmpDir, "crossplane", "another"), "should not generate claims without infra configuration")
+}
+
+func TestJenkinsJobsOPABundle(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.OPAPolicyTemplateDir = filepath.Join("test_data", "opa")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	path := filepath.Join(tmpDir, "opa", "myjenkins", jobs.OPABundleFile)
+	f, err := os.Open(path)
+	require.NoError(t, err, "failed to open bundle %s", path)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err, "failed to read gzip bundle %s", path)
+	tr := tar.NewReader(gz)
+
+	policies := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "failed to read bundle %s", path)
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err, "failed to read %s in bundle %s", header.Name, path)
+		policies[header.Name] = string(data)
+	}
+	require.Len(t, policies, 2, "policies in bundle %s", path)
+	assert.Contains(t, policies["jobs.rego"], "package jenkins.myjenkins\n", "jobs policy")
+	assert.Contains(t, policies["jobs.rego"], `"myapp"`, "jobs policy")
+	assert.Contains(t, policies["jobs.rego"], `,"another"`, "jobs policy")
+	assert.Contains(t, policies["scm.rego"], `"https://github.com/myorg/myapp.git"`, "scm policy")
+}
+
+func TestJenkinsJobsPulumi(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.PulumiTemplateDir = filepath.Join("test_data", "pulumi")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	dir := filepath.Join(tmpDir, "pulumi", "myjenkins")
+	assert.FileExists(t, filepath.Join(dir, "Pulumi.myjenkins.yaml"), "should have generated the stack file")
+	path := filepath.Join(dir, "index.ts")
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Contains(t, string(data), `FileAsset("../../myjenkins/values.yaml")`, "generated file %s", path)
+}
+
+func TestJenkinsJobsMaxTemplateSize(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.MaxTemplateSize = 100
+
+	err = o.Run()
+	require.Error(t, err, "should have failed as the template is too large")
+	assert.Contains(t, err.Error(), "exceeds the --max-template-size of 100 bytes")
+}
+
+func TestJenkinsJobsHelmChartVersion(t *testing.T) {
+	testCases := map[string]string{
+		"2.19.0":                    "master",
+		"3.0.0":                     "controller",
+		jobs.LatestHelmChartVersion: "controller",
+	}
+	for version, expected := range testCases {
+		key, err := jobs.ValuesKeyForChartVersion(version)
+		require.NoError(t, err, "failed to find values key for version %s", version)
+		assert.Equal(t, expected, key, "values key for version %s", version)
+	}
+
+	_, err := jobs.ValuesKeyForChartVersion("latest")
+	require.Error(t, err, "should fail for an invalid version")
+
+	for version, expected := range map[string]string{"": jobs.DefaultValuesKey, jobs.LatestHelmChartVersion: "controller"} {
+		tmpDir, err := ioutil.TempDir("", "")
+		require.NoError(t, err, "could not create temp dir")
+
+		_, o := jobs.NewCmdJenkinsJobs()
+		o.OutDir = tmpDir
+		o.Dir = "test_data"
+		o.HelmChartVersion = version
+
+		err = o.Run()
+		require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+		valuesFile := filepath.Join(tmpDir, "myjenkins", "values.yaml")
+		values := map[string]interface{}{}
+		err = yamls.LoadFile(valuesFile, &values)
+		require.NoError(t, err, "failed to load file %s", valuesFile)
+		assert.Len(t, values, 1, "should only have a single values key in %s for helm chart version %s", valuesFile, version)
+		assert.NotNil(t, values[expected], "should have the %s values key in %s for helm chart version %s", expected, valuesFile, version)
+	}
+}
+
+func TestJenkinsJobsChart(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ChartName = "jenkins-jobs"
+	o.ChartVersion = "1.2.3"
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	chartFile := filepath.Join(tmpDir, "myjenkins", "Chart.yaml")
+	chart := &jobs.ChartMetadata{}
+	err = yamls.LoadFile(chartFile, chart)
+	require.NoError(t, err, "failed to load file %s", chartFile)
+	assert.Equal(t, "jenkins-jobs", chart.Name, "chart name")
+	assert.Equal(t, "1.2.3", chart.Version, "chart version")
+}
+
+func TestJenkinsJobsConfigMapLabels(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.EmitConfigMap = true
+	o.Labels = []string{"team=cheese"}
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	cmFile := filepath.Join(tmpDir, "myjenkins", "myjenkins-jobs-cm.yaml")
+	cm := &corev1.ConfigMap{}
+	err = yamls.LoadFile(cmFile, cm)
+	require.NoError(t, err, "failed to load file %s", cmFile)
+
+	assert.Equal(t, "cheese", cm.Labels["team"], "label team")
+	assert.Equal(t, jobs.ManagedByValue, cm.Labels[jobs.ManagedByLabel], "label %s", jobs.ManagedByLabel)
+	assert.NotEmpty(t, cm.Data["myapp.xml"], "ConfigMap data for myapp.xml")
+}
+
+func TestJenkinsJobsMerge(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	valuesFile := filepath.Join(tmpDir, "myjenkins", "values.yaml")
+	err = os.MkdirAll(filepath.Dir(valuesFile), files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create dir for %s", valuesFile)
+	err = ioutil.WriteFile(valuesFile, []byte("master:\n  installPlugins:\n  - git:4.4.5\n  jobs:\n    removed: <project/>\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", valuesFile)
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.Merge = true
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	values := map[string]interface{}{}
+	err = yamls.LoadFile(valuesFile, &values)
+	require.NoError(t, err, "failed to load file %s", valuesFile)
+
+	master, ok := values["master"].(map[string]interface{})
+	require.True(t, ok, "should have a master map in %s", valuesFile)
+	assert.Equal(t, []interface{}{"git:4.4.5"}, master["installPlugins"], "master.installPlugins")
+
+	jobsMap, ok := master["jobs"].(map[string]interface{})
+	require.True(t, ok, "should have a master.jobs map in %s", valuesFile)
+	assert.NotNil(t, jobsMap["myapp"], "master.jobs.myapp")
+	assert.Nil(t, jobsMap["removed"], "master.jobs.removed")
+}
+
+func TestJenkinsJobsOutputCompression(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	for i := 0; i < 2; i++ {
+		_, o := jobs.NewCmdJenkinsJobs()
+		o.OutDir = tmpDir
+		o.Dir = "test_data"
+		o.OutputCompression = jobs.OutputCompressionGzip
+		o.Merge = true
+
+		err = o.Run()
+		require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+	}
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "myjenkins", "values.yaml"), "should not have generated uncompressed values")
+	valuesFile := filepath.Join(tmpDir, "myjenkins", "values.yaml.gz")
+	f, err := os.Open(valuesFile)
+	require.NoError(t, err, "failed to open file %s", valuesFile)
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	require.NoError(t, err, "failed to create gzip reader for %s", valuesFile)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err, "failed to decompress file %s", valuesFile)
+
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal(data, &values)
+	require.NoError(t, err, "failed to parse file %s", valuesFile)
+	jobsMap, _, err := unstructured.NestedMap(values, "master", "jobs")
+	require.NoError(t, err, "failed to get the jobs of %s", valuesFile)
+	assert.NotNil(t, jobsMap["myapp"], "master.jobs.myapp")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.OutputCompression = "zip"
+	err = o.Run()
+	require.Error(t, err, "should have failed to validate the output compression")
+}
+
+func TestJenkinsJobsOperator(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.ConfigFile = filepath.Join("test_data", "operator", "source-config.yaml")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "myjenkins", "values.yaml"), "should not have generated helm values")
+
+	path := filepath.Join(tmpDir, "myjenkins", "jenkins-cr.yaml")
+	cr := map[string]interface{}{}
+	err = yamls.LoadFile(path, &cr)
+	require.NoError(t, err, "failed to load file %s", path)
+
+	assert.Equal(t, "Jenkins", cr["kind"], "kind in %s", path)
+	assert.Contains(t, maps.GetMapValueAsStringViaPath(cr, "spec.jobs.myapp"), "https://github.com/myorg/myapp.git", "job in %s", path)
+
+	spec, ok := cr["spec"].(map[string]interface{})
+	require.True(t, ok, "should have a spec in %s", path)
+	containers, ok := maps.GetMapValueViaPath(spec, "master.containers").([]interface{})
+	require.True(t, ok && len(containers) == 1, "should have a container in %s", path)
+	assert.Equal(t, jobs.DefaultJenkinsImage, containers[0].(map[string]interface{})["image"], "image in %s", path)
+	roles, ok := spec["roles"].([]interface{})
+	require.True(t, ok, "should have roles in %s", path)
+	assert.Len(t, roles, 1, "roles in %s", path)
+}
+
+func TestJenkinsJobsCredentialsConfigMap(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "jenkins-credentials",
+			Namespace: "jx",
+		},
+		Data: map[string]string{
+			"authToken": "mytoken",
+		},
+	})
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.CredentialsConfigMap = "jx/jenkins-credentials"
+	o.KubeClient = kubeClient
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	path := filepath.Join(tmpDir, "myjenkins", "values.yaml")
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Contains(t, string(data), "<authToken>mytoken</authToken>", "values file %s", path)
+}
+
+func TestJenkinsJobsEnvValues(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := jobs.NewCmdJenkinsJobs()
+	o.OutDir = tmpDir
+	o.Dir = "test_data"
+	o.Env = "staging"
+	o.EnvValuesFile = filepath.Join("test_data", "env", "staging.yaml")
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command in dir %s", tmpDir)
+
+	path := filepath.Join(tmpDir, "myjenkins", "values-staging.yaml")
+	overlay := map[string]interface{}{}
+	err = yamls.LoadFile(path, &overlay)
+	require.NoError(t, err, "failed to load file %s", path)
+
+	assert.Equal(t, "4Gi", maps.GetMapValueAsStringViaPath(overlay, "master.resources.limits.memory"), "memory in %s", path)
+	assert.Equal(t, "<flow-definition/>\n", maps.GetMapValueAsStringViaPath(overlay, "master.jobs.myapp"), "job in %s", path)
+	assert.Nil(t, maps.GetMapValueViaPath(overlay, "master.jobs.another"), "should not include unchanged jobs in %s", path)
 }
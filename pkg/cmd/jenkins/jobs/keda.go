@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/templater"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const scaledJobTemplate = "scaled-job.yaml.gotmpl"
+
+// writeScaledJobs renders the KEDA ScaledJob of each job of the server with keda configuration into a single file
+func (o *Options) writeScaledJobs(server string, configs []*JenkinsTemplateConfig) error {
+	templateFile := filepath.Join(o.KEDATemplateDir, scaledJobTemplate)
+	var docs []string
+	var text string
+	for _, c := range configs {
+		if c.KEDA == nil {
+			continue
+		}
+		if text == "" {
+			data, err := ioutil.ReadFile(templateFile)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load template file %s", templateFile)
+			}
+			text = string(data)
+		}
+
+		templateData := map[string]interface{}{}
+		for k, v := range c.TemplateData {
+			templateData[k] = v
+		}
+		templateData["Server"] = server
+		templateData["ScaledJobMinReplicas"] = c.KEDA.MinReplicas
+		templateData["ScaledJobMaxReplicas"] = c.KEDA.MaxReplicas
+		templateData["JenkinsQueueLabel"] = c.KEDA.QueueLabel
+
+		o.logTemplateData(templateFile, templateData)
+		output, err := templater.Evaluate(o.templateFuncMap(), templateData, text, templateFile, "KEDA ScaledJob "+c.Key)
+		if err != nil {
+			return errors.Wrapf(err, "failed to evaluate template %s", templateFile)
+		}
+		output = strings.TrimPrefix(strings.TrimSpace(output), "---\n")
+		if output != "" {
+			docs = append(docs, output+"\n")
+		}
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	dir := filepath.Join(o.OutDir, "keda", server)
+	err := os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	path := filepath.Join(dir, ScaledJobsFile)
+	err = ioutil.WriteFile(path, []byte(strings.Join(docs, "---\n")), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	log.Logger().Infof("created file %s", info(path))
+	return nil
+}
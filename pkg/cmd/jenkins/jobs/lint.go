@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// DangerousTemplateFunctions the template functions which expose the environment of the process generating the jobs
+var DangerousTemplateFunctions = []string{"env", "expandenv", "exec"}
+
+// LintTemplate checks the template text for syntax errors, undefined or dangerous functions and templates
+// which always produce empty output returning a description of each issue found
+func LintTemplate(funcMap template.FuncMap, name, text string) []string {
+	t, err := template.New(name).Funcs(funcMap).Parse(text)
+	if err != nil {
+		msg := err.Error()
+		if strings.Contains(msg, "function ") && strings.Contains(msg, " not defined") {
+			return []string{"undefined function: " + msg}
+		}
+		return []string{"syntax error: " + msg}
+	}
+
+	var issues []string
+	for _, nt := range t.Templates() {
+		if nt.Tree == nil || nt.Tree.Root == nil {
+			continue
+		}
+		for _, fn := range dangerousFunctions(nt.Tree.Root) {
+			issue := fmt.Sprintf("dangerous function: the %s function exposes the environment of the process", fn)
+			if nt.Name() != name {
+				issue += " in template " + nt.Name()
+			}
+			issues = append(issues, issue)
+		}
+	}
+	if t.Tree == nil || t.Tree.Root == nil || !producesOutput(t.Tree.Root) {
+		issues = append(issues, "empty output: the template always produces empty output")
+	}
+	return issues
+}
+
+// lintTemplate lints the given template once logging any issues as warnings or returning an error if --lint-strict is enabled
+func (o *Options) lintTemplate(funcMap template.FuncMap, name, text string) error {
+	if o.lintedTemplates[name] {
+		return nil
+	}
+	o.lintedTemplates[name] = true
+
+	issues := LintTemplate(funcMap, name, text)
+	for _, issue := range issues {
+		log.Logger().Warnf("template %s: %s", name, issue)
+	}
+	if o.LintStrict && len(issues) > 0 {
+		return errors.Errorf("template %s has %d lint issue(s): %s", name, len(issues), strings.Join(issues, "; "))
+	}
+	return nil
+}
+
+// dangerousFunctions returns the names of the dangerous functions used in the given node
+func dangerousFunctions(node parse.Node) []string {
+	var answer []string
+	walkTemplateNodes(node, func(n parse.Node) {
+		id, ok := n.(*parse.IdentifierNode)
+		if !ok {
+			return
+		}
+		for _, fn := range DangerousTemplateFunctions {
+			if id.Ident == fn {
+				answer = append(answer, fn)
+			}
+		}
+	})
+	return answer
+}
+
+// producesOutput returns true if the node contains any text or actions which can produce output
+func producesOutput(node parse.Node) bool {
+	answer := false
+	walkTemplateNodes(node, func(n parse.Node) {
+		switch t := n.(type) {
+		case *parse.TextNode:
+			if strings.TrimSpace(string(t.Text)) != "" {
+				answer = true
+			}
+		case *parse.ActionNode:
+			if t.Pipe != nil && len(t.Pipe.Decl) == 0 {
+				answer = true
+			}
+		case *parse.TemplateNode:
+			answer = true
+		}
+	})
+	return answer
+}
+
+// walkTemplateNodes invokes the function for the node and all of its descendants
+func walkTemplateNodes(node parse.Node, fn func(parse.Node)) {
+	if node == nil {
+		return
+	}
+	fn(node)
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkTemplateNodes(c, fn)
+		}
+	case *parse.ActionNode:
+		walkTemplateNodes(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walkTemplateNodes(c, fn)
+		}
+	case *parse.CommandNode:
+		for _, c := range n.Args {
+			walkTemplateNodes(c, fn)
+		}
+	case *parse.IfNode:
+		walkBranchNodes(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranchNodes(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranchNodes(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkTemplateNodes(n.Pipe, fn)
+	}
+}
+
+func walkBranchNodes(n *parse.BranchNode, fn func(parse.Node)) {
+	walkTemplateNodes(n.Pipe, fn)
+	if n.List != nil {
+		walkTemplateNodes(n.List, fn)
+	}
+	if n.ElseList != nil {
+		walkTemplateNodes(n.ElseList, fn)
+	}
+}
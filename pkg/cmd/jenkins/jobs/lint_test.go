@@ -0,0 +1,54 @@
+package jobs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/sprig"
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintTemplate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name: "valid",
+			text: `<project><description>{{ .Repository | upper }}</description></project>`,
+		},
+		{
+			name:     "syntax",
+			text:     `<project>{{ if .Repository }}</project>`,
+			expected: "syntax error",
+		},
+		{
+			name:     "undefined",
+			text:     `<project>{{ .Repository | cheese }}</project>`,
+			expected: "undefined function",
+		},
+		{
+			name:     "dangerous",
+			text:     `<project>{{ env "HOME" }}</project>`,
+			expected: "dangerous function",
+		},
+		{
+			name:     "empty",
+			text:     "{{ $name := .Repository }}\n{{ if $name }}\n{{ end }}\n",
+			expected: "empty output",
+		},
+	}
+
+	for _, tc := range testCases {
+		issues := jobs.LintTemplate(sprig.TxtFuncMap(), tc.name, tc.text)
+		if tc.expected == "" {
+			assert.Empty(t, issues, "issues for %s", tc.name)
+			continue
+		}
+		require.Len(t, issues, 1, "issues for %s", tc.name)
+		assert.True(t, strings.HasPrefix(issues[0], tc.expected+":"), "issue %s for %s", issues[0], tc.name)
+	}
+}
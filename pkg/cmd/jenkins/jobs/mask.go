@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// MaskedValue the value logged in place of a masked template data value
+const MaskedValue = "***"
+
+// DefaultMaskPatterns the patterns of template data keys which are always masked in log output
+var DefaultMaskPatterns = []string{"password", "token", "secret", "key"}
+
+// MaskTemplateData returns a copy of the template data with the values of the given keys and any keys
+// matching the DefaultMaskPatterns replaced with the MaskedValue
+func MaskTemplateData(data map[string]interface{}, maskKeys []string) map[string]interface{} {
+	answer := map[string]interface{}{}
+	for k, v := range data {
+		if isMaskedKey(k, maskKeys) {
+			answer[k] = MaskedValue
+			continue
+		}
+		switch m := v.(type) {
+		case map[string]interface{}:
+			answer[k] = MaskTemplateData(m, maskKeys)
+		case map[string]string:
+			values := map[string]interface{}{}
+			for mk, mv := range m {
+				values[mk] = mv
+			}
+			answer[k] = MaskTemplateData(values, maskKeys)
+		default:
+			answer[k] = v
+		}
+	}
+	return answer
+}
+
+func isMaskedKey(key string, maskKeys []string) bool {
+	lower := strings.ToLower(key)
+	for _, p := range DefaultMaskPatterns {
+		if strings.Contains(lower, p) {
+			return true
+		}
+	}
+	for _, k := range maskKeys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// logTemplateData logs the masked template data used to render the given template
+func (o *Options) logTemplateData(templateFile string, data map[string]interface{}) {
+	text, err := json.Marshal(MaskTemplateData(data, o.MaskKeys))
+	if err != nil {
+		log.Logger().Debugf("failed to marshal the template data of %s: %s", templateFile, err.Error())
+		return
+	}
+	log.Logger().Debugf("rendering template %s with data %s", templateFile, string(text))
+}
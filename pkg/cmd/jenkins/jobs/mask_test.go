@@ -0,0 +1,42 @@
+package jobs_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskTemplateData(t *testing.T) {
+	data := map[string]interface{}{
+		"Owner":      "myorg",
+		"Repository": "myapp",
+		"Webhook":    "https://hooks.example.com/abc",
+		"Credentials": map[string]string{
+			"username": "admin",
+			"password": "s3cr3t",
+		},
+		"Sonar": map[string]interface{}{
+			"ProjectKey": "myorg:myapp",
+			"ServerURL":  "https://sonar.example.com",
+		},
+	}
+
+	masked := jobs.MaskTemplateData(data, []string{"webhook"})
+
+	expected := map[string]interface{}{
+		"Owner":      "myorg",
+		"Repository": "myapp",
+		"Webhook":    jobs.MaskedValue,
+		"Credentials": map[string]interface{}{
+			"username": "admin",
+			"password": jobs.MaskedValue,
+		},
+		"Sonar": map[string]interface{}{
+			"ProjectKey": jobs.MaskedValue,
+			"ServerURL":  "https://sonar.example.com",
+		},
+	}
+	assert.Equal(t, expected, masked, "masked template data")
+	assert.Equal(t, "https://hooks.example.com/abc", data["Webhook"], "the original template data should not be modified")
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// OPABundleFile the name of the OPA policy bundle generated for each server
+	OPABundleFile = "bundle.tar.gz"
+
+	opaTemplateSuffix = ".rego.gotmpl"
+)
+
+// writeOPABundle renders the Rego policy templates for the jobs of the server and packages them as an OPA bundle
+func (o *Options) writeOPABundle(server string, configs []*JenkinsTemplateConfig) error {
+	templates, err := filepath.Glob(filepath.Join(o.OPAPolicyTemplateDir, "*"+opaTemplateSuffix))
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the Rego templates in dir %s", o.OPAPolicyTemplateDir)
+	}
+	if len(templates) == 0 {
+		return errors.Errorf("no %s templates found in dir %s", opaTemplateSuffix, o.OPAPolicyTemplateDir)
+	}
+	sort.Strings(templates)
+
+	var jobs []string
+	var repositories []interface{}
+	for _, c := range configs {
+		jobs = append(jobs, c.Key)
+		repositories = append(repositories, c.TemplateData)
+	}
+	templateData := map[string]interface{}{
+		"Server":       server,
+		"Jobs":         jobs,
+		"Repositories": repositories,
+	}
+
+	bundleDir, err := ioutil.TempDir("", "jx-opa-bundle-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(bundleDir)
+
+	for _, t := range templates {
+		name := filepath.Base(t)
+		path := filepath.Join(bundleDir, strings.TrimSuffix(name, ".gotmpl"))
+		err = o.renderTemplate(o.OPAPolicyTemplateDir, name, path, templateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to render Rego template %s", t)
+		}
+	}
+
+	dir := filepath.Join(o.OutDir, "opa", server)
+	err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	path := filepath.Join(dir, OPABundleFile)
+	_, err = packageDir(bundleDir, "", path, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to package OPA bundle %s", path)
+	}
+	log.Logger().Infof("created OPA bundle %s", info(path))
+	return nil
+}
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// JenkinsOperatorAPIVersion the API version of the Jenkins Operator custom resource
+	JenkinsOperatorAPIVersion = "jenkins.io/v1alpha2"
+
+	// DefaultJenkinsImage the default container image of the Jenkins master
+	DefaultJenkinsImage = "jenkins/jenkins:lts"
+)
+
+// writeJenkinsCR writes a Jenkins Operator custom resource for the server with the rendered jobs inline
+func (o *Options) writeJenkinsCR(dir string, serverConfig *v1alpha1.JenkinsServerConfig, jobs map[string]interface{}) error {
+	image := serverConfig.Image
+	if image == "" {
+		image = DefaultJenkinsImage
+	}
+
+	metadata := map[string]interface{}{
+		"name": serverConfig.Server,
+	}
+	if len(o.labels) > 0 {
+		metadata["labels"] = o.labels
+	}
+	spec := map[string]interface{}{
+		"master": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":            "jenkins-master",
+					"image":           image,
+					"imagePullPolicy": "IfNotPresent",
+				},
+			},
+		},
+		"jobs": jobs,
+	}
+	if len(serverConfig.Roles) > 0 {
+		spec["roles"] = serverConfig.Roles
+	}
+	cr := map[string]interface{}{
+		"apiVersion": JenkinsOperatorAPIVersion,
+		"kind":       "Jenkins",
+		"metadata":   metadata,
+		"spec":       spec,
+	}
+
+	path := filepath.Join(dir, "jenkins-cr.yaml")
+	log.Logger().Infof("creating Jenkins custom resource file %s", path)
+	err := yamls.SaveFile(cr, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
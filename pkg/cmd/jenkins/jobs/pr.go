@@ -0,0 +1,388 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitdiscovery"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/scmhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	prLong = templates.LongDesc(`
+		Generates the Jenkins Jobs helm files and creates a GitHub Pull Request with any changes
+
+The files are generated into a temporary directory and compared with the current output directory. If there are any changes the output directory is updated, removing any files which are no longer generated, and the changes are committed to a new branch which is pushed and a Pull Request including the diff is created.
+`)
+
+	prExample = templates.Examples(`
+		# create a pull request with any changes to the generated files
+		%s jenkins jobs pr --github-token-file /secrets/github/token
+	`)
+)
+
+// PROptions the options for the pr command
+type PROptions struct {
+	Options
+	PRTitle         string
+	PRBaseBranch    string
+	PRBranch        string
+	Repository      string
+	GitServerURL    string
+	GitHubTokenFile string
+	ScmClient       *scm.Client
+	CommandRunner   cmdrunner.CommandRunner
+	PullRequest     *scm.PullRequest
+}
+
+// NewCmdJenkinsJobsPR creates a command object for the command
+func NewCmdJenkinsJobsPR() (*cobra.Command, *PROptions) {
+	o := &PROptions{}
+
+	cmd := &cobra.Command{
+		Use:     "pr",
+		Short:   "Generates the Jenkins Jobs helm files and creates a GitHub Pull Request with any changes",
+		Long:    prLong,
+		Example: fmt.Sprintf(prExample, rootcmd.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the current working directory which should be a git clone of the repository")
+	o.AddFlags(cmd)
+	cmd.Flags().StringVarP(&o.PRTitle, "pr-title", "", "chore: regenerate the Jenkins jobs", "the title of the Pull Request and the commit message")
+	cmd.Flags().StringVarP(&o.PRBaseBranch, "pr-base-branch", "", "master", "the branch the Pull Request is created against")
+	cmd.Flags().StringVarP(&o.PRBranch, "pr-branch", "", "", "the branch the changes are pushed to. If not specified a branch name is generated")
+	cmd.Flags().StringVarP(&o.Repository, "repo", "r", "", "the full name of the GitHub repository such as myorg/myrepo. If not specified it is discovered from the git remote of the current directory")
+	cmd.Flags().StringVarP(&o.GitServerURL, "git-server", "", "https://github.com", "the URL of the GitHub server")
+	cmd.Flags().StringVarP(&o.GitHubTokenFile, "github-token-file", "", "", "the file containing the GitHub token used to create the Pull Request. If not specified the git credentials or $GIT_TOKEN are used")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *PROptions) Run() error {
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.QuietCommandRunner
+	}
+	outDir := o.OutDir
+	if outDir == "" {
+		outDir = filepath.Join(o.Dir, "jenkins")
+	}
+	outPath, err := gitRelativePath(o.Dir, outDir)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "jenkins-jobs-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	o.OutDir = tmpDir
+	o.mergeDir = outDir
+	err = o.Options.Run()
+	o.OutDir = outDir
+	o.mergeDir = ""
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate the Jenkins jobs")
+	}
+
+	diff, err := diffDirs(outDir, tmpDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to compare the generated files with %s", outDir)
+	}
+	stale, err := staleFiles(outDir, tmpDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the files in %s which are no longer generated", outDir)
+	}
+	for _, rel := range stale {
+		text, err := readFile(filepath.Join(outDir, rel))
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		diff += "--- a/" + rel + "\n+++ /dev/null\n" + diffLines(string(text), "")
+	}
+	if diff == "" {
+		log.Logger().Infof("no changes to the generated files in %s", info(outDir))
+		return nil
+	}
+
+	if o.Repository == "" {
+		gitInfo, err := gitdiscovery.FindGitInfoFromDir(o.Dir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to discover the git repository in dir %s", o.Dir)
+		}
+		o.Repository = scm.Join(gitInfo.Organisation, gitInfo.Name)
+	}
+	if o.PRBranch == "" {
+		o.PRBranch = fmt.Sprintf("jenkins-jobs-%d", time.Now().Unix())
+	}
+
+	err = o.git("checkout", "-b", o.PRBranch)
+	if err != nil {
+		return err
+	}
+	for _, rel := range stale {
+		path := filepath.Join(outDir, rel)
+		err = os.Remove(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to remove file %s", path)
+		}
+		// lets remove the dir too if it is now empty
+		_ = os.Remove(filepath.Dir(path))
+	}
+	err = files.CopyDirOverwrite(tmpDir, outDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy the generated files to %s", outDir)
+	}
+	for _, args := range [][]string{
+		{"add", "-A", outPath},
+		{"commit", "-m", o.PRTitle},
+		{"push", "origin", o.PRBranch},
+	} {
+		err = o.git(args...)
+		if err != nil {
+			return err
+		}
+	}
+	return o.createPullRequest(diff)
+}
+
+// gitRelativePath returns the path of the output dir relative to the git clone dir the git commands are run in
+func gitRelativePath(dir, outDir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find absolute path of %s", dir)
+	}
+	absOutDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find absolute path of %s", outDir)
+	}
+	rel, err := filepath.Rel(absDir, absOutDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("the output dir %s must be inside the git clone dir %s", outDir, dir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (o *PROptions) createPullRequest(diff string) error {
+	if o.ScmClient == nil {
+		token := ""
+		if o.GitHubTokenFile != "" {
+			data, err := ioutil.ReadFile(o.GitHubTokenFile)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load file %s", o.GitHubTokenFile)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		var err error
+		o.ScmClient, _, err = scmhelpers.NewScmClient("github", o.GitServerURL, token)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create the GitHub client for %s", o.GitServerURL)
+		}
+	}
+
+	input := &scm.PullRequestInput{
+		Title: o.PRTitle,
+		Head:  o.PRBranch,
+		Base:  o.PRBaseBranch,
+		Body:  "The generated Jenkins jobs have changed:\n\n```diff\n" + diff + "```\n",
+	}
+	ctx := context.Background()
+	pr, _, err := o.ScmClient.PullRequests.Create(ctx, o.Repository, input)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create Pull Request on repository %s", o.Repository)
+	}
+	o.PullRequest = pr
+	log.Logger().Infof("created Pull Request %s on repository %s", info(pr.Link), info(o.Repository))
+	return nil
+}
+
+func (o *PROptions) git(args ...string) error {
+	c := &cmdrunner.Command{
+		Dir:  o.Dir,
+		Name: "git",
+		Args: args,
+	}
+	_, err := o.CommandRunner(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to run command %s", c.CLI())
+	}
+	return nil
+}
+
+// diffDirs returns the differences of the files in the new dir compared to the same files in the old dir
+func diffDirs(oldDir, newDir string) (string, error) {
+	buf := strings.Builder{}
+	err := filepath.Walk(newDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(newDir, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find relative path of %s", path)
+		}
+		newData, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		oldPath := filepath.Join(oldDir, rel)
+		oldText := ""
+		exists, err := files.FileExists(oldPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if file exists %s", oldPath)
+		}
+		if exists {
+			oldData, err := readFile(oldPath)
+			if err != nil {
+				return err
+			}
+			oldText = string(oldData)
+		}
+		if oldText == string(newData) {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		buf.WriteString("--- a/" + rel + "\n+++ b/" + rel + "\n")
+		buf.WriteString(diffLines(oldText, string(newData)))
+		return nil
+	})
+	return buf.String(), err
+}
+
+// generatedServerFiles the files which identify a dir of the old dir as the output of a Jenkins server
+var generatedServerFiles = []string{"values.yaml", "values.yaml" + gzipExtension, "jenkins-cr.yaml"}
+
+// namedFileSuffixes the suffixes of generated files whose names start with the name of a server or repository
+var namedFileSuffixes = []string{"-cm.yaml", "-eventlistener.yaml", "-kustomization.yaml"}
+
+// generatedFileKind returns the kind of a generated file ignoring any compression and server or repository name
+func generatedFileKind(name string) string {
+	name = strings.TrimSuffix(name, gzipExtension)
+	if strings.HasPrefix(name, "values-") {
+		return "values-"
+	}
+	for _, suffix := range namedFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return suffix
+		}
+	}
+	return name
+}
+
+// generatedKinds returns the kinds of the files generated in the dir
+func generatedKinds(dir string) (map[string]bool, error) {
+	answer := map[string]bool{}
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			answer[generatedFileKind(fi.Name())] = true
+		}
+		return nil
+	})
+	return answer, err
+}
+
+// staleFiles returns the paths relative to the old dir of the files which are no longer generated.
+//
+// These are the files in any dir the new files are generated into which are not generated any more along with
+// the files of any Jenkins server which is no longer generated. Only files of the kinds generated into the new dir
+// are returned so that other files, such as templates or files generated with other options, are left alone
+func staleFiles(oldDir, newDir string) ([]string, error) {
+	kinds, err := generatedKinds(newDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the kinds of files generated in %s", newDir)
+	}
+	var answer []string
+	err = filepath.Walk(oldDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == oldDir {
+				return nil
+			}
+			return err
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(oldDir, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find relative path of %s", path)
+		}
+		if fi.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		generated, err := files.DirExists(filepath.Join(newDir, rel))
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if dir exists %s", filepath.Join(newDir, rel))
+		}
+		if !generated {
+			if filepath.Dir(rel) != "." || !isServerDir(path) {
+				return filepath.SkipDir
+			}
+			// the Jenkins server has been removed from the source config
+			return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() || !kinds[generatedFileKind(info.Name())] {
+					return err
+				}
+				r, err := filepath.Rel(oldDir, p)
+				answer = append(answer, r)
+				return err
+			})
+		}
+		if rel == "." {
+			return nil
+		}
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read dir %s", path)
+		}
+		for _, info := range infos {
+			if info.IsDir() || !kinds[generatedFileKind(info.Name())] {
+				continue
+			}
+			r := filepath.Join(rel, info.Name())
+			exists, err := files.FileExists(filepath.Join(newDir, r))
+			if err != nil {
+				return errors.Wrapf(err, "failed to check if file exists %s", filepath.Join(newDir, r))
+			}
+			if !exists {
+				answer = append(answer, r)
+			}
+		}
+		return nil
+	})
+	return answer, err
+}
+
+// isServerDir returns true if the dir contains the generated files of a Jenkins server
+func isServerDir(dir string) bool {
+	for _, name := range generatedServerFiles {
+		exists, err := files.FileExists(filepath.Join(dir, name))
+		if err == nil && exists {
+			return true
+		}
+	}
+	return false
+}
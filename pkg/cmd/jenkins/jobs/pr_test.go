@@ -0,0 +1,140 @@
+package jobs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJenkinsJobsPR(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	runner := &fakerunner.FakeRunner{}
+	scmClient, _ := fake.NewDefault()
+
+	_, o := jobs.NewCmdJenkinsJobsPR()
+	o.Dir = "test_data"
+	o.OutDir = filepath.Join(tmpDir, "jenkins")
+	o.Repository = "myorg/environment"
+	o.CommandRunner = runner.Run
+	o.ScmClient = scmClient
+
+	err = o.Run()
+	require.Error(t, err, "should fail as the output dir is not inside the git clone dir")
+	assert.Nil(t, o.PullRequest, "should not create a Pull Request")
+}
+
+func TestJenkinsJobsPRRelativeDir(t *testing.T) {
+	repoDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	err = files.CopyDirOverwrite("test_data", repoDir)
+	require.NoError(t, err, "failed to copy test data to %s", repoDir)
+
+	outDir := filepath.Join(repoDir, "jenkins")
+	existing := map[string]string{
+		filepath.Join("myjenkins", "values.yaml"):            "master:\n  installPlugins:\n  - git:4.4.5\n  jobs:\n    removed: <project/>\n",
+		filepath.Join("myjenkins", "old.yaml"):               "old: true\n",
+		filepath.Join("myjenkins", "values.yaml.gz"):         "master:\n  jobs:\n    compressed: <project/>\n",
+		filepath.Join("myjenkins", "Chart.yaml"):             "name: jenkins-jobs\n",
+		filepath.Join("myjenkins", "myjenkins-jobs-cm.yaml"): "kind: ConfigMap\n",
+		filepath.Join("myjenkins", "changes.diff"):           "+ old change\n",
+		filepath.Join("oldjenkins", "values.yaml"):           "master:\n  jobs:\n    old: <project/>\n",
+	}
+	for name, text := range existing {
+		path := filepath.Join(outDir, name)
+		err = os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir for %s", path)
+		data := []byte(text)
+		if strings.HasSuffix(name, ".gz") {
+			buf := &bytes.Buffer{}
+			w := gzip.NewWriter(buf)
+			_, err = w.Write(data)
+			require.NoError(t, err, "failed to compress %s", path)
+			require.NoError(t, w.Close(), "failed to compress %s", path)
+			data = buf.Bytes()
+		}
+		err = ioutil.WriteFile(path, data, files.DefaultFileWritePermissions)
+		require.NoError(t, err, "failed to save file %s", path)
+	}
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err, "failed to get the current dir")
+	dir, err := filepath.Rel(cwd, repoDir)
+	require.NoError(t, err, "failed to find relative path of %s", repoDir)
+
+	runner := &fakerunner.FakeRunner{}
+	scmClient, fakeData := fake.NewDefault()
+
+	_, o := jobs.NewCmdJenkinsJobsPR()
+	o.Dir = dir
+	o.Merge = true
+	o.Repository = "myorg/environment"
+	o.PRBranch = "regenerate-jobs"
+	o.CommandRunner = runner.Run
+	o.ScmClient = scmClient
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command")
+
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{CLI: "git checkout -b regenerate-jobs"},
+		fakerunner.FakeResult{CLI: "git add -A jenkins"},
+		fakerunner.FakeResult{CLI: "git commit -m chore: regenerate the Jenkins jobs"},
+		fakerunner.FakeResult{CLI: "git push origin regenerate-jobs"},
+	)
+
+	valuesFile := filepath.Join(outDir, "myjenkins", "values.yaml")
+	values := map[string]interface{}{}
+	err = yamls.LoadFile(valuesFile, &values)
+	require.NoError(t, err, "failed to load file %s", valuesFile)
+	master, ok := values["master"].(map[string]interface{})
+	require.True(t, ok, "should have a master map in %s", valuesFile)
+	assert.Equal(t, []interface{}{"git:4.4.5"}, master["installPlugins"], "should have merged with the existing values in %s", valuesFile)
+	jobsMap, ok := master["jobs"].(map[string]interface{})
+	require.True(t, ok, "should have a master.jobs map in %s", valuesFile)
+	assert.NotNil(t, jobsMap["myapp"], "master.jobs.myapp")
+	assert.Nil(t, jobsMap["removed"], "master.jobs.removed")
+
+	assert.NoFileExists(t, filepath.Join(outDir, "myjenkins", "values.yaml.gz"), "should have removed the file which is no longer generated")
+	assert.NoDirExists(t, filepath.Join(outDir, "oldjenkins"), "should have removed the files of the server which is no longer generated")
+	for _, name := range []string{"old.yaml", "Chart.yaml", "myjenkins-jobs-cm.yaml", "changes.diff"} {
+		assert.FileExists(t, filepath.Join(outDir, "myjenkins", name), "should not remove files of kinds which are not generated")
+	}
+	assert.FileExists(t, filepath.Join(outDir, "templates", "default.xml.gotmpl"), "should not remove the templates")
+
+	require.NotNil(t, o.PullRequest, "should have created a Pull Request")
+	input := fakeData.PullRequestsCreated[o.PullRequest.Number]
+	require.NotNil(t, input, "should have created a Pull Request")
+	assert.Equal(t, "regenerate-jobs", input.Head, "head branch")
+	assert.Equal(t, "master", input.Base, "base branch")
+	assert.Contains(t, input.Body, "+++ b/myjenkins/values.yaml", "Pull Request body")
+	assert.Contains(t, input.Body, "-    removed: <project/>", "Pull Request body")
+	assert.NotContains(t, input.Body, "-  installPlugins:", "Pull Request body")
+	assert.Contains(t, input.Body, "--- a/myjenkins/values.yaml.gz\n+++ /dev/null", "Pull Request body")
+	assert.NotContains(t, input.Body, "--- a/myjenkins/Chart.yaml", "Pull Request body")
+	assert.Contains(t, input.Body, "--- a/oldjenkins/values.yaml\n+++ /dev/null", "Pull Request body")
+
+	_, o = jobs.NewCmdJenkinsJobsPR()
+	o.Dir = dir
+	o.Merge = true
+	o.Repository = "myorg/environment"
+	o.CommandRunner = runner.Run
+	o.ScmClient = scmClient
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run the command again")
+	assert.Nil(t, o.PullRequest, "should not create a Pull Request when there are no changes")
+}
@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/templater"
+	"github.com/pkg/errors"
+)
+
+// writePulumiProgram renders the Pulumi program templates for the server into the pulumi directory
+func (o *Options) writePulumiProgram(dir, server string) error {
+	outDir := filepath.Join(o.OutDir, "pulumi", server)
+	valuesFile := filepath.Join(dir, o.valuesFileName())
+	rel, err := filepath.Rel(outDir, valuesFile)
+	if err == nil {
+		valuesFile = filepath.ToSlash(rel)
+	}
+	templateData := map[string]interface{}{
+		"Server":       server,
+		"ValuesFile":   valuesFile,
+		"ChartName":    o.ChartName,
+		"ChartVersion": o.ChartVersion,
+		"Labels":       o.labels,
+	}
+
+	fileInfos, err := ioutil.ReadDir(o.PulumiTemplateDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read dir %s", o.PulumiTemplateDir)
+	}
+	for _, f := range fileInfos {
+		templateName := f.Name()
+		if f.IsDir() || !strings.HasSuffix(templateName, ".gotmpl") {
+			continue
+		}
+		name, err := templater.Evaluate(o.templateFuncMap(), templateData, strings.TrimSuffix(templateName, ".gotmpl"), templateName, "file name")
+		if err != nil {
+			return errors.Wrapf(err, "failed to evaluate file name %s", templateName)
+		}
+		err = o.renderTemplate(o.PulumiTemplateDir, templateName, filepath.Join(outDir, name), templateData)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
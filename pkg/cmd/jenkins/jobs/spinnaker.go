@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/httphelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// DefaultSpinnakerPipeline the default name of the Spinnaker pipeline of a repository
+const DefaultSpinnakerPipeline = "deploy"
+
+// writeSpinnakerPipeline renders the Spinnaker pipeline JSON of the repository and saves it via the Gate API if a URL is specified
+func (o *Options) writeSpinnakerPipeline(group *v1alpha1.RepositoryGroup, repo *v1alpha1.Repository) error {
+	application := repo.Spinnaker.Application
+	if application == "" {
+		application = repo.Name
+	}
+	pipeline := repo.Spinnaker.Pipeline
+	if pipeline == "" {
+		pipeline = DefaultSpinnakerPipeline
+	}
+	templateData := o.createTemplateData(group, repo)
+	templateData["SpinnakerApplication"] = application
+	templateData["SpinnakerPipeline"] = pipeline
+	path := filepath.Join(o.OutDir, "spinnaker", application, pipeline+".json")
+	err := o.renderTemplate(o.SpinnakerTemplateDir, "pipeline.json.gotmpl", path, templateData)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate Spinnaker pipeline")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+	if !json.Valid(data) {
+		return errors.Errorf("the generated Spinnaker pipeline %s is not valid JSON", path)
+	}
+	if o.SpinnakerGateURL == "" {
+		return nil
+	}
+	return o.saveSpinnakerPipeline(path, data)
+}
+
+// saveSpinnakerPipeline saves the pipeline using the Spinnaker Gate API
+func (o *Options) saveSpinnakerPipeline(path string, data []byte) error {
+	if o.HTTPClient == nil {
+		o.HTTPClient = httphelpers.GetClient()
+	}
+	u := stringhelpers.UrlJoin(o.SpinnakerGateURL, "pipelines")
+	req, err := http.NewRequest("POST", u, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create http request for %s", u)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to POST endpoint %s", u)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("failed to POST Spinnaker pipeline %s to %s with status %s: %s", path, u, resp.Status, string(body))
+	}
+	log.Logger().Infof("saved Spinnaker pipeline %s to %s", info(path), info(o.SpinnakerGateURL))
+	return nil
+}
@@ -0,0 +1,268 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-gitops/pkg/sourceconfigs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/httphelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	validateLiveLong = fmt.Sprintf(templates.LongDesc(`
+		Validates the source config against the live Jenkins servers using their REST API
+
+For each Jenkins server it verifies the server is reachable, that the generated jobs do not conflict with existing jobs which are not managed by this command and that all the referenced credentials exist.
+
+A job is considered managed if it is one of the jobs in the previously generated values files of the server in the output directory or if its description contains 'jx-gitops'.
+
+The API token is read from the --token-file or the $%s environment variable so that it is not visible in the process list.
+`), JenkinsAPITokenEnvVar)
+
+	validateLiveExample = templates.Examples(`
+		# validate the jobs against a Jenkins server
+		%s jenkins jobs validate-live --jenkins-url-map myjenkins=https://jenkins.example.com --username admin --token-file /secrets/jenkins/token
+	`)
+)
+
+// JenkinsAPITokenEnvVar the environment variable containing the API token used to authenticate with the Jenkins servers
+const JenkinsAPITokenEnvVar = "JENKINS_API_TOKEN"
+
+// ValidateLiveOptions the options for the validate-live command
+type ValidateLiveOptions struct {
+	Options
+	JenkinsURLMap []string
+	Username      string
+	TokenFile     string
+	Token         string
+	jenkinsURLs   map[string]string
+}
+
+type jenkinsJobList struct {
+	Jobs []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"jobs"`
+}
+
+type jenkinsCredentialList struct {
+	Credentials []struct {
+		ID string `json:"id"`
+	} `json:"credentials"`
+}
+
+// NewCmdJenkinsJobsValidateLive creates a command object for the command
+func NewCmdJenkinsJobsValidateLive() (*cobra.Command, *ValidateLiveOptions) {
+	o := &ValidateLiveOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "validate-live",
+		Short:   "Validates the source config against the live Jenkins servers using their REST API",
+		Long:    validateLiveLong,
+		Example: fmt.Sprintf(validateLiveExample, rootcmd.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the current working directory")
+	cmd.Flags().StringVarP(&o.ConfigFile, "config", "c", "", "the configuration file to load for the repository configurations. If not specified we look in ./.jx/gitops/source-config.yaml")
+	cmd.Flags().StringArrayVarP(&o.JenkinsURLMap, "jenkins-url-map", "", nil, "the URL of each Jenkins server of the form server=url")
+	cmd.Flags().StringVarP(&o.Username, "username", "u", "", "the user name used to authenticate with the Jenkins servers")
+	cmd.Flags().StringVarP(&o.TokenFile, "token-file", "", "", "the file containing the API token used to authenticate with the Jenkins servers. If not specified the $"+JenkinsAPITokenEnvVar+" environment variable is used")
+	cmd.Flags().StringVarP(&o.OutDir, "out", "o", "", "the output directory of the previously generated config files used to find the managed jobs. If not specified defaults to the jenkins dir in the current directory")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *ValidateLiveOptions) Run() error {
+	o.jenkinsURLs = map[string]string{}
+	for _, text := range o.JenkinsURLMap {
+		values := strings.SplitN(text, "=", 2)
+		if len(values) != 2 || values[0] == "" || values[1] == "" {
+			return options.InvalidOptionf("jenkins-url-map", text, "should be of the form server=url")
+		}
+		o.jenkinsURLs[values[0]] = values[1]
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = httphelpers.GetClient()
+	}
+	if o.Token == "" {
+		if o.TokenFile != "" {
+			data, err := ioutil.ReadFile(o.TokenFile)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load file %s", o.TokenFile)
+			}
+			o.Token = strings.TrimSpace(string(data))
+		} else {
+			o.Token = os.Getenv(JenkinsAPITokenEnvVar)
+		}
+	}
+
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	serverJobs := map[string][]string{}
+	for _, sc := range o.SourceConfig.Spec.JenkinsServers {
+		serverJobs[sc.Server] = nil
+	}
+	config := &o.SourceConfig
+	for i := range config.Spec.Groups {
+		group := &config.Spec.Groups[i]
+		for j := range group.Repositories {
+			repo := &group.Repositories[j]
+			sourceconfigs.DefaultValues(config, group, repo)
+			if repo.Jenkins == nil || repo.Jenkins.Server == "" {
+				continue
+			}
+			server := repo.Jenkins.Server
+			serverJobs[server] = append(serverJobs[server], repo.Name)
+		}
+	}
+
+	var servers []string
+	for server := range serverJobs {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+
+	var problems []string
+	for _, server := range servers {
+		problems = append(problems, o.validateServer(server, serverJobs[server])...)
+	}
+	for _, p := range problems {
+		log.Logger().Errorf("%s", p)
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("found %d problems validating the Jenkins servers", len(problems))
+	}
+	log.Logger().Infof("validated %d Jenkins servers", len(servers))
+	return nil
+}
+
+// validateServer returns the problems found validating the jobs and credentials on the given server
+func (o *ValidateLiveOptions) validateServer(server string, jobs []string) []string {
+	u := o.jenkinsURLs[server]
+	if u == "" {
+		return []string{fmt.Sprintf("no --jenkins-url-map entry for Jenkins server %s", server)}
+	}
+
+	jobList := &jenkinsJobList{}
+	err := o.getJSON(stringhelpers.UrlJoin(u, "api/json?tree=jobs[name,description]"), jobList)
+	if err != nil {
+		return []string{fmt.Sprintf("Jenkins server %s is not reachable: %s", server, err.Error())}
+	}
+
+	managed, err := o.generatedJobs(server)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to find the previously generated jobs of Jenkins server %s: %s", server, err.Error())}
+	}
+
+	var problems []string
+	for _, job := range jobList.Jobs {
+		if stringhelpers.StringArrayIndex(jobs, job.Name) >= 0 && !managed[job.Name] && !strings.Contains(job.Description, ManagedByValue) {
+			problems = append(problems, fmt.Sprintf("job %s conflicts with an existing job on Jenkins server %s which is not managed by %s", job.Name, server, ManagedByValue))
+		}
+	}
+
+	serverConfig := o.jenkinsServerConfig(server)
+	if serverConfig == nil || len(serverConfig.Credentials) == 0 {
+		return problems
+	}
+	credentialList := &jenkinsCredentialList{}
+	err = o.getJSON(stringhelpers.UrlJoin(u, "credentials/store/system/domain/_/api/json?tree=credentials[id]"), credentialList)
+	if err != nil {
+		return append(problems, fmt.Sprintf("failed to list the credentials on Jenkins server %s: %s", server, err.Error()))
+	}
+	ids := map[string]bool{}
+	for _, c := range credentialList.Credentials {
+		ids[c.ID] = true
+	}
+	for _, c := range serverConfig.Credentials {
+		if !ids[c.ID] {
+			problems = append(problems, fmt.Sprintf("credential %s does not exist on Jenkins server %s", c.ID, server))
+		}
+	}
+	return problems
+}
+
+// generatedJobs returns the names of the jobs in the previously generated values files of the server
+func (o *ValidateLiveOptions) generatedJobs(server string) (map[string]bool, error) {
+	answer := map[string]bool{}
+	for _, name := range []string{"values.yaml", "values.yaml" + gzipExtension} {
+		path := filepath.Join(o.OutDir, server, name)
+		exists, err := files.FileExists(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check if file exists %s", path)
+		}
+		if !exists {
+			continue
+		}
+		data, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]interface{}{}
+		err = yaml.Unmarshal(data, &values)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse file %s", path)
+		}
+		for _, key := range []string{"master", "controller"} {
+			jobs, _, err := unstructured.NestedMap(values, key, "jobs")
+			if err != nil {
+				continue
+			}
+			for job := range jobs {
+				answer[job] = true
+			}
+		}
+	}
+	return answer, nil
+}
+
+func (o *ValidateLiveOptions) getJSON(u string, result interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create http request for %s", u)
+	}
+	if o.Username != "" || o.Token != "" {
+		req.SetBasicAuth(o.Username, o.Token)
+	}
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to GET endpoint %s", u)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read response from %s", u)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to GET endpoint %s with status %s", u, resp.Status)
+	}
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse JSON response from %s", u)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package jobs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/h2non/gock"
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/jenkins/jobs"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/httphelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJenkinsJobsValidateLive(t *testing.T) {
+	client := httphelpers.GetClient()
+	gock.InterceptClient(client)
+
+	defer gock.Off()
+	defer gock.RestoreClient(client)
+
+	testCases := []struct {
+		name        string
+		jobs        string
+		credentials string
+		fail        bool
+	}{
+		{
+			name:        "valid",
+			jobs:        `{"jobs": [{"name": "myapp", "description": "managed by jx-gitops"}, {"name": "other"}]}`,
+			credentials: `{"credentials": [{"id": "git-credentials"}]}`,
+		},
+		{
+			name:        "conflict",
+			jobs:        `{"jobs": [{"name": "myapp", "description": "created by hand"}]}`,
+			credentials: `{"credentials": [{"id": "git-credentials"}]}`,
+			fail:        true,
+		},
+		{
+			name:        "missing-credentials",
+			jobs:        `{"jobs": []}`,
+			credentials: `{"credentials": []}`,
+			fail:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		gock.New("https://jenkins.example.com").
+			Get("/api/json").
+			Reply(200).
+			BodyString(tc.jobs)
+		gock.New("https://jenkins.example.com").
+			Get("/credentials/store/system/domain/_/api/json").
+			Reply(200).
+			BodyString(tc.credentials)
+
+		_, o := jobs.NewCmdJenkinsJobsValidateLive()
+		o.Dir = "test_data"
+		o.JenkinsURLMap = []string{"myjenkins=https://jenkins.example.com"}
+		o.HTTPClient = client
+
+		err := o.Run()
+		if tc.fail {
+			require.Error(t, err, "should have failed for %s", tc.name)
+		} else {
+			require.NoError(t, err, "should not have failed for %s", tc.name)
+		}
+		gock.Flush()
+	}
+
+	_, o := jobs.NewCmdJenkinsJobsValidateLive()
+	o.Dir = "test_data"
+	o.HTTPClient = client
+	err := o.Run()
+	require.Error(t, err, "should have failed when there is no URL for the server")
+}
+
+func TestJenkinsJobsValidateLiveGeneratedJobs(t *testing.T) {
+	client := httphelpers.GetClient()
+	gock.InterceptClient(client)
+
+	defer gock.Off()
+	defer gock.RestoreClient(client)
+
+	outDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	valuesFile := filepath.Join(outDir, "myjenkins", "values.yaml")
+	err = os.MkdirAll(filepath.Dir(valuesFile), files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create dir for %s", valuesFile)
+	err = ioutil.WriteFile(valuesFile, []byte("master:\n  jobs:\n    myapp: <flow-definition/>\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", valuesFile)
+
+	tokenFile := filepath.Join(outDir, "token")
+	err = ioutil.WriteFile(tokenFile, []byte("mytoken\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", tokenFile)
+
+	// the basic auth header of admin:mytoken
+	auth := "Basic YWRtaW46bXl0b2tlbg=="
+	gock.New("https://jenkins.example.com").
+		Get("/api/json").
+		MatchHeader("Authorization", auth).
+		Reply(200).
+		BodyString(`{"jobs": [{"name": "myapp"}, {"name": "another", "description": "created by hand"}]}`)
+	gock.New("https://jenkins.example.com").
+		Get("/credentials/store/system/domain/_/api/json").
+		MatchHeader("Authorization", auth).
+		Reply(200).
+		BodyString(`{"credentials": [{"id": "git-credentials"}]}`)
+
+	_, o := jobs.NewCmdJenkinsJobsValidateLive()
+	o.Dir = "test_data"
+	o.OutDir = outDir
+	o.JenkinsURLMap = []string{"myjenkins=https://jenkins.example.com"}
+	o.Username = "admin"
+	o.TokenFile = tokenFile
+	o.HTTPClient = client
+
+	err = o.Run()
+	require.Error(t, err, "should have failed as the another job was not generated previously")
+	assert.Contains(t, err.Error(), "found 1 problems", "should only report the job which was not generated previously")
+	assert.True(t, gock.IsDone(), "should have authenticated with the token")
+}
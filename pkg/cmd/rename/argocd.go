@@ -0,0 +1,105 @@
+package rename
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// updateArgoCDApps updates any ArgoCD Application whose spec.source.path is a dir containing renamed files.
+//
+// The path itself is a dir which is not changed by renaming files. Instead the file names in the
+// spec.source.directory.include and the resources of any kustomization file in the dir are updated
+func (o *Options) updateArgoCDApps() error {
+	renames := map[string]string{}
+	for canonical, original := range o.CreateInverseMap() {
+		renames[filepath.ToSlash(original)] = filepath.ToSlash(canonical)
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+	dirs := renamedDirs(renames)
+
+	dir := o.ArgoCDAppDir
+	if dir == "" {
+		dir = o.Dir
+	}
+	filter := kyamls.Filter{
+		Kinds: []string{"argoproj.io/Application"},
+	}
+	modifyFn := func(node *yaml.RNode, path string) (bool, error) {
+		sourcePath := kyamls.GetStringField(node, path, "spec", "source", "path")
+		if sourcePath == "" {
+			return false, nil
+		}
+		sourceDir := filepath.ToSlash(filepath.Clean(sourcePath))
+		if !dirs[sourceDir] {
+			return false, nil
+		}
+		err := o.updateKustomizeResources("Application", o.relativePath(path), sourceDir, renames)
+		if err != nil {
+			return false, err
+		}
+
+		include := kyamls.GetStringField(node, path, "spec", "source", "directory", "include")
+		if include == "" {
+			return false, nil
+		}
+		newInclude, files := renamedIncludeFiles(sourceDir, include, renames)
+		if newInclude == include {
+			return false, nil
+		}
+		err = kyamls.SetStringValue(node, path, newInclude, "spec", "source", "directory", "include")
+		if err != nil {
+			return false, err
+		}
+		for _, f := range files {
+			o.references = append(o.references, reference{Kind: "Application", Path: o.relativePath(path), File: f})
+		}
+		log.Logger().Infof("updated ArgoCD Application %s directory include %s => %s", path, include, newInclude)
+		return true, nil
+	}
+	err := kyamls.ModifyFiles(dir, modifyFn, filter)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update ArgoCD Applications in dir %s", dir)
+	}
+	return nil
+}
+
+// renamedIncludeFiles replaces the renamed file names in a directory include which is either a file name or a
+// {a.yaml,b.yaml} list of file names. Glob patterns are left as they are. The canonical paths of the renamed
+// files are returned too
+func renamedIncludeFiles(dir, include string, renames map[string]string) (string, []string) {
+	text := include
+	brace := strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}")
+	if brace {
+		text = text[1 : len(text)-1]
+	}
+	var canonicals []string
+	names := strings.Split(text, ",")
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.ContainsAny(name, "*?[{") {
+			continue
+		}
+		canonical := renames[path.Join(dir, name)]
+		if canonical == "" {
+			continue
+		}
+		canonicals = append(canonicals, canonical)
+		names[i] = path.Base(canonical)
+	}
+	if len(canonicals) == 0 {
+		return include, nil
+	}
+	text = strings.Join(names, ",")
+	if brace {
+		text = "{" + text + "}"
+	}
+	return text, canonicals
+}
@@ -0,0 +1,131 @@
+package rename
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// backupFile copies the given file into the backup dir using the same path relative to the source dir
+func (o *Options) backupFile(path string) error {
+	rel, err := filepath.Rel(o.Dir, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find relative path of %s in dir %s", path, o.Dir)
+	}
+	backupPath := filepath.Join(o.BackupDir, rel)
+	err = os.MkdirAll(filepath.Dir(backupPath), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", filepath.Dir(backupPath))
+	}
+	err = files.CopyFile(path, backupPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy %s to %s", path, backupPath)
+	}
+	return nil
+}
+
+// BackupManifestFile the file in the backup dir which records the renames so they can be restored
+const BackupManifestFile = ".renames.yaml"
+
+// BackupManifest the renames of the files copied into the backup dir in the order they were made
+type BackupManifest struct {
+	Renames []BackupRename `json:"renames,omitempty"`
+}
+
+// BackupRename a file which was backed up and then renamed. The paths are relative to the source dir
+type BackupRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// loadBackupManifest loads the manifest of the renames in the backup dir if it exists
+func (o *Options) loadBackupManifest() (*BackupManifest, error) {
+	manifest := &BackupManifest{}
+	path := filepath.Join(o.BackupDir, BackupManifestFile)
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return manifest, nil
+	}
+	err = yamls.LoadFile(path, manifest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load backup manifest %s", path)
+	}
+	return manifest, nil
+}
+
+// saveBackupManifest appends the renames made by this run to the manifest in the backup dir
+func (o *Options) saveBackupManifest() error {
+	if len(o.backupRenames) == 0 {
+		return nil
+	}
+	manifest, err := o.loadBackupManifest()
+	if err != nil {
+		return err
+	}
+	manifest.Renames = append(manifest.Renames, o.backupRenames...)
+	path := filepath.Join(o.BackupDir, BackupManifestFile)
+	err = yamls.SaveFile(manifest, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save backup manifest %s", path)
+	}
+	return nil
+}
+
+// restoreBackup reverses the renames recorded in the backup manifest, in the reverse order they were made,
+// by removing the renamed files and copying the backed up files back to their original location
+func (o *Options) restoreBackup() error {
+	exists, err := files.DirExists(o.BackupDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if dir exists %s", o.BackupDir)
+	}
+	if !exists {
+		return errors.Errorf("the backup dir %s does not exist", o.BackupDir)
+	}
+	manifest, err := o.loadBackupManifest()
+	if err != nil {
+		return err
+	}
+
+	for i := len(manifest.Renames) - 1; i >= 0; i-- {
+		rename := manifest.Renames[i]
+		backupPath := filepath.Join(o.BackupDir, rename.From)
+		path := filepath.Join(o.Dir, rename.From)
+		newPath := filepath.Join(o.Dir, rename.To)
+
+		err = os.Remove(newPath)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove renamed file %s", newPath)
+		}
+		err = os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create dir %s", filepath.Dir(path))
+		}
+		err = files.CopyFile(backupPath, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy %s to %s", backupPath, path)
+		}
+		log.Logger().Infof("restored %s", rename.From)
+	}
+	return nil
+}
+
+// recordBackupRename records a rename of a backed up file so that it can be restored
+func (o *Options) recordBackupRename(path, newPath string) error {
+	from, err := filepath.Rel(o.Dir, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find relative path of %s in dir %s", path, o.Dir)
+	}
+	to, err := filepath.Rel(o.Dir, newPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find relative path of %s in dir %s", newPath, o.Dir)
+	}
+	o.backupRenames = append(o.backupRenames, BackupRename{From: from, To: to})
+	return nil
+}
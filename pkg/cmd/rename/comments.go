@@ -0,0 +1,40 @@
+package rename
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseCommentMetadata parses the metadata of the form '# kind: Deployment, name: frontend' from the first line of the file
+func parseCommentMetadata(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open file %s", path)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	line := strings.TrimSpace(scanner.Text())
+	if !strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+	answer := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(line, "#"), ",") {
+		values := strings.SplitN(field, ":", 2)
+		if len(values) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(values[0]))
+		value := strings.TrimSpace(values[1])
+		if key != "" && value != "" {
+			answer[key] = value
+		}
+	}
+	return answer, nil
+}
@@ -0,0 +1,159 @@
+package rename
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ComposeFiles the names of the Docker Compose files which are updated
+var ComposeFiles = []string{"docker-compose.yml", "docker-compose.yaml"}
+
+// updateComposeFiles updates the volume, config and secret file references in the Docker Compose files which reference a renamed file
+func (o *Options) updateComposeFiles() error {
+	renames := map[string]string{}
+	for canonical, original := range o.CreateInverseMap() {
+		renames[filepath.ToSlash(original)] = filepath.ToSlash(canonical)
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	err := filepath.Walk(o.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" || info.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, name := range ComposeFiles {
+			if info.Name() == name {
+				return o.updateComposeFile(path, renames)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update Docker Compose files in dir %s", o.Dir)
+	}
+	return nil
+}
+
+func (o *Options) updateComposeFile(path string, renames map[string]string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+	nodes, err := (&kio.ByteReader{Reader: bytes.NewReader(data), OmitReaderAnnotations: true}).Read()
+	if err != nil {
+		log.Logger().Warnf("failed to parse Docker Compose file %s so not updating its file references: %s", path, err.Error())
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	modified := false
+	updateValue := func(value *yaml.Node, source string, replace func(string) string) {
+		// only host paths are files, other volume sources are named volumes
+		if !strings.HasPrefix(source, ".") {
+			return
+		}
+		canonical, newSource := o.renamedFileReference(dir, source, renames)
+		if newSource == "" {
+			return
+		}
+		if !strings.HasPrefix(newSource, ".") {
+			newSource = "./" + newSource
+		}
+		newValue := replace(newSource)
+		log.Logger().Infof("updated Docker Compose file %s reference %s => %s", o.relativePath(path), value.Value, newValue)
+		o.references = append(o.references, reference{Kind: "Compose", Path: o.relativePath(path), File: canonical})
+		value.Value = newValue
+		modified = true
+	}
+
+	for _, node := range nodes {
+		services, err := node.Pipe(yaml.Lookup("services"))
+		if err != nil {
+			return errors.Wrapf(err, "failed to find services")
+		}
+		if services != nil {
+			err = services.VisitFields(func(service *yaml.MapNode) error {
+				volumes, err := service.Value.Pipe(yaml.Lookup("volumes"))
+				if err != nil || volumes == nil {
+					return err
+				}
+				return volumes.VisitElements(func(volume *yaml.RNode) error {
+					value := volume.YNode()
+					if value.Kind == yaml.ScalarNode {
+						// short syntax of SOURCE:TARGET[:MODE]
+						parts := strings.SplitN(value.Value, ":", 2)
+						updateValue(value, parts[0], func(newSource string) string {
+							parts[0] = newSource
+							return strings.Join(parts, ":")
+						})
+						return nil
+					}
+					source := volume.Field("source")
+					if source != nil && source.Value.YNode().Kind == yaml.ScalarNode {
+						value = source.Value.YNode()
+						updateValue(value, value.Value, func(newSource string) string {
+							return newSource
+						})
+					}
+					return nil
+				})
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to update service volumes")
+			}
+		}
+
+		for _, section := range []string{"configs", "secrets"} {
+			m, err := node.Pipe(yaml.Lookup(section))
+			if err != nil {
+				return errors.Wrapf(err, "failed to find %s", section)
+			}
+			if m == nil {
+				continue
+			}
+			err = m.VisitFields(func(entry *yaml.MapNode) error {
+				file := entry.Value.Field("file")
+				if file != nil && file.Value.YNode().Kind == yaml.ScalarNode {
+					value := file.Value.YNode()
+					updateValue(value, value.Value, func(newSource string) string {
+						return newSource
+					})
+				}
+				return nil
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to update %s", section)
+			}
+		}
+	}
+	if !modified {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err = (&kio.ByteWriter{Writer: &buf}).Write(nodes)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal Docker Compose file %s", path)
+	}
+	err = ioutil.WriteFile(path, buf.Bytes(), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
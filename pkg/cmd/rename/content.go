@@ -0,0 +1,83 @@
+package rename
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// checkDuplicateContent reports files with identical content and returns true if the file should not be
+// renamed as the file at its canonical path has identical content
+func (o *Options) checkDuplicateContent(r *FileResult) (bool, error) {
+	hash, err := contentHash(r.Path)
+	if err != nil {
+		return false, err
+	}
+	if o.contentHashes == nil {
+		o.contentHashes = map[string]string{}
+	}
+	if first, ok := o.contentHashes[hash]; ok {
+		log.Logger().Warnf("file %s has identical content to %s", r.Path, first)
+	} else {
+		o.contentHashes[hash] = r.Path
+	}
+
+	if o.OutputDir != "" || r.Canonical == r.Path {
+		return false, nil
+	}
+	exists, err := files.FileExists(r.Canonical)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check if file exists %s", r.Canonical)
+	}
+	if !exists {
+		return false, nil
+	}
+	canonicalHash, err := contentHash(r.Canonical)
+	if err != nil {
+		return false, err
+	}
+	if canonicalHash != hash {
+		return false, nil
+	}
+	log.Logger().Infof("not renaming %s as it is a duplicate of %s", r.Path, r.Canonical)
+	return true, nil
+}
+
+// checkDuplicateInDir warns if the file has identical content to another file in the same directory
+func (o *Options) checkDuplicateInDir(r *FileResult) error {
+	hash, err := contentHash(r.Path)
+	if err != nil {
+		return err
+	}
+	if o.dirContentHashes == nil {
+		o.dirContentHashes = map[string]map[string]string{}
+	}
+	dir := filepath.Dir(r.Path)
+	hashes := o.dirContentHashes[dir]
+	if hashes == nil {
+		hashes = map[string]string{}
+		o.dirContentHashes[dir] = hashes
+	}
+	if first, ok := hashes[hash]; ok {
+		log.Logger().Warnf("file %s has identical content to %s in the same directory", r.Path, first)
+		r.Duplicate = first
+		return nil
+	}
+	hashes[hash] = r.Path
+	return nil
+}
+
+// contentHash returns the SHA-256 hash of the file content
+func contentHash(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load file %s", path)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,37 @@
+package rename
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/pkg/errors"
+)
+
+// csvHeader the header row of the CSV file
+var csvHeader = []string{"original", "canonical", "kind", "name", "action", "error"}
+
+// writeCSV writes the report entries as a CSV file with every field quoted
+func (o *Options) writeCSV() error {
+	buf := &strings.Builder{}
+	writeCSVRow(buf, csvHeader)
+	for _, e := range o.CreateReport() {
+		writeCSVRow(buf, []string{e.Path, e.Canonical, e.Kind, e.Name, e.Action, e.Error})
+	}
+	err := ioutil.WriteFile(o.EmitCSV, []byte(buf.String()), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save CSV file %s", o.EmitCSV)
+	}
+	return nil
+}
+
+// writeCSVRow writes the fields quoted as the encoding/csv package only quotes fields when required
+func writeCSVRow(buf *strings.Builder, fields []string) {
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(`"` + strings.ReplaceAll(f, `"`, `""`) + `"`)
+	}
+	buf.WriteString("\n")
+}
@@ -0,0 +1,48 @@
+package rename
+
+import (
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// updateFluxResources updates the kustomization resources in the dirs referenced by the spec.path of any Flux
+// Kustomization which contain renamed files. The spec.path is a dir and renaming never changes the dir of a file
+// so the Kustomization itself is never modified
+func (o *Options) updateFluxResources() error {
+	renames := map[string]string{}
+	for canonical, original := range o.CreateInverseMap() {
+		renames[filepath.ToSlash(original)] = filepath.ToSlash(canonical)
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+	dirs := renamedDirs(renames)
+
+	dir := o.FluxDir
+	if dir == "" {
+		dir = o.Dir
+	}
+	filter := kyamls.Filter{
+		Kinds: []string{"kustomize.toolkit.fluxcd.io/Kustomization"},
+	}
+	modifyFn := func(node *yaml.RNode, path string) (bool, error) {
+		sourcePath := kyamls.GetStringField(node, path, "spec", "path")
+		if sourcePath == "" {
+			return false, nil
+		}
+		sourceDir := filepath.ToSlash(filepath.Clean(sourcePath))
+		if !dirs[sourceDir] {
+			return false, nil
+		}
+		err := o.updateKustomizeResources(kyamls.GetKind(node, path), o.relativePath(path), sourceDir, renames)
+		return false, err
+	}
+	err := kyamls.ModifyFiles(dir, modifyFn, filter)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update Flux resources in dir %s", dir)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package rename
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"github.com/awalterschulze/gographviz"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/pkg/errors"
+)
+
+// reference a resource such as an ArgoCD Application which references a renamed file
+type reference struct {
+	Kind string
+	Path string
+	File string
+}
+
+// CreateGraph creates a DOT graph of the renamed files and the resources which reference them
+func (o *Options) CreateGraph() (string, error) {
+	type edge struct {
+		from  string
+		to    string
+		label string
+	}
+	var edges []edge
+	for _, r := range o.Results {
+		if r.Action == ActionRenamed {
+			edges = append(edges, edge{o.relativePath(r.Path), o.relativePath(r.Canonical), "renamed"})
+		}
+	}
+	for _, ref := range o.references {
+		edges = append(edges, edge{ref.Kind + ": " + ref.Path, ref.File, "references"})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		return edges[i].to < edges[j].to
+	})
+
+	graph, err := newGraph("rename")
+	if err != nil {
+		return "", err
+	}
+	for _, e := range edges {
+		for _, node := range []string{e.from, e.to} {
+			err = graph.AddNode("rename", node, nil)
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to add node %s", node)
+			}
+		}
+		err = graph.AddEdge(e.from, e.to, true, map[string]string{"label": e.label})
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to add edge from %s to %s", e.from, e.to)
+		}
+	}
+	ast, err := graph.WriteAst()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to write graph")
+	}
+	return ast.String(), nil
+}
+
+// newGraph creates a new directed graph which escapes node names and attributes
+func newGraph(name string) (*gographviz.Escape, error) {
+	graph := gographviz.NewEscape()
+	err := graph.SetName(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to set graph name")
+	}
+	err = graph.SetDir(true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to set graph as directed")
+	}
+	err = graph.AddAttr(name, "rankdir", "LR")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to set graph rankdir")
+	}
+	return graph, nil
+}
+
+func (o *Options) writeGraph() error {
+	text, err := o.CreateGraph()
+	if err != nil {
+		return errors.Wrapf(err, "failed to create graph")
+	}
+	if o.GraphFile == "" {
+		_, err = fmt.Fprint(o.Out, text)
+		return err
+	}
+	err = ioutil.WriteFile(o.GraphFile, []byte(text), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save graph file %s", o.GraphFile)
+	}
+	return nil
+}
+
+// CreateKindGraph creates a DOT graph of the number of files of each kind
+func (o *Options) CreateKindGraph() (string, error) {
+	counts := map[string]int{}
+	for _, r := range o.Results {
+		if r.Kind != "" {
+			counts[r.Kind]++
+		}
+	}
+	var kinds []string
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	root := o.Dir
+	graph, err := newGraph("kinds")
+	if err != nil {
+		return "", err
+	}
+	err = graph.AddNode("kinds", root, map[string]string{"shape": "folder"})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to add node %s", root)
+	}
+	for _, kind := range kinds {
+		attrs := map[string]string{
+			"shape": "box",
+			"label": fmt.Sprintf("%s\\n%d files", kind, counts[kind]),
+		}
+		err = graph.AddNode("kinds", kind, attrs)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to add node %s", kind)
+		}
+		err = graph.AddEdge(root, kind, true, map[string]string{"label": strconv.Itoa(counts[kind])})
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to add edge from %s to %s", root, kind)
+		}
+	}
+	ast, err := graph.WriteAst()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to write graph")
+	}
+	return ast.String(), nil
+}
+
+func (o *Options) writeKindGraph() error {
+	text, err := o.CreateKindGraph()
+	if err != nil {
+		return errors.Wrapf(err, "failed to create graph")
+	}
+	err = ioutil.WriteFile(o.EmitGraphviz, []byte(text), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save graph file %s", o.EmitGraphviz)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package rename
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// updateHelmfiles updates the values file references in the helmfile.yaml and helmfile.d/*.yaml files which reference a renamed file
+func (o *Options) updateHelmfiles() error {
+	renames := map[string]string{}
+	for canonical, original := range o.CreateInverseMap() {
+		renames[filepath.ToSlash(original)] = filepath.ToSlash(canonical)
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(o.Dir, "helmfile.d", "*.yaml"))
+	if err != nil {
+		return errors.Wrapf(err, "failed to find helmfiles in dir %s", o.Dir)
+	}
+	path := filepath.Join(o.Dir, "helmfile.yaml")
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if exists {
+		paths = append([]string{path}, paths...)
+	}
+	for _, path := range paths {
+		err = o.updateHelmfile(path, renames)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update helmfile %s", path)
+		}
+	}
+	return nil
+}
+
+func (o *Options) updateHelmfile(path string, renames map[string]string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+	nodes, err := (&kio.ByteReader{Reader: bytes.NewReader(data), OmitReaderAnnotations: true}).Read()
+	if err != nil {
+		log.Logger().Warnf("failed to parse helmfile %s so not updating its values references: %s", path, err.Error())
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	modified := false
+	updateValues := func(values *yaml.RNode) error {
+		if values == nil || values.YNode().Kind != yaml.SequenceNode {
+			return nil
+		}
+		for _, value := range values.YNode().Content {
+			if value.Kind != yaml.ScalarNode {
+				continue
+			}
+			canonical, newValue := o.renamedFileReference(dir, value.Value, renames)
+			if newValue == "" {
+				continue
+			}
+			log.Logger().Infof("updated helmfile %s values %s => %s", o.relativePath(path), value.Value, newValue)
+			o.references = append(o.references, reference{Kind: "Helmfile", Path: o.relativePath(path), File: canonical})
+			value.Value = newValue
+			modified = true
+		}
+		return nil
+	}
+
+	for _, node := range nodes {
+		releases, err := node.Pipe(yaml.Lookup("releases"))
+		if err != nil {
+			return errors.Wrapf(err, "failed to find releases")
+		}
+		if releases != nil {
+			err = releases.VisitElements(func(release *yaml.RNode) error {
+				values, err := release.Pipe(yaml.Lookup("values"))
+				if err != nil {
+					return err
+				}
+				return updateValues(values)
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to update release values")
+			}
+		}
+
+		environments, err := node.Pipe(yaml.Lookup("environments"))
+		if err != nil {
+			return errors.Wrapf(err, "failed to find environments")
+		}
+		if environments != nil {
+			err = environments.VisitFields(func(env *yaml.MapNode) error {
+				values, err := env.Value.Pipe(yaml.Lookup("values"))
+				if err != nil {
+					return err
+				}
+				return updateValues(values)
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to update environment values")
+			}
+		}
+	}
+	if !modified {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err = (&kio.ByteWriter{Writer: &buf}).Write(nodes)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal helmfile %s", path)
+	}
+	err = ioutil.WriteFile(path, buf.Bytes(), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
+
+// relativeReferencePath returns the path of the referenced file relative to the source dir
+func (o *Options) relativeReferencePath(dir, value string) string {
+	rel, err := filepath.Rel(o.Dir, filepath.Join(dir, value))
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// renamedFileReference returns the canonical path and the new file reference relative to the dir of the referencing file
+// or empty strings if the file was not renamed
+func (o *Options) renamedFileReference(dir, value string, renames map[string]string) (string, string) {
+	if strings.Contains(value, "{{") {
+		return "", ""
+	}
+	canonical := renames[o.relativeReferencePath(dir, value)]
+	if canonical == "" {
+		return "", ""
+	}
+	rel, err := filepath.Rel(dir, filepath.Join(o.Dir, canonical))
+	if err != nil {
+		return "", ""
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasPrefix(value, "./") && !strings.HasPrefix(rel, ".") {
+		rel = "./" + rel
+	}
+	return canonical, rel
+}
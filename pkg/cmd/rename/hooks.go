@@ -0,0 +1,32 @@
+package rename
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// runHook runs the given hook script passing the directory, the number of renamed files and whether
+// this is a read only run as environment variables
+func (o *Options) runHook(name, script string) error {
+	c := &cmdrunner.Command{
+		Name: script,
+		Env: map[string]string{
+			"RENAME_DIR":     o.Dir,
+			"RENAME_COUNT":   strconv.Itoa(o.CreateSummary().Renamed),
+			"RENAME_DRY_RUN": strconv.FormatBool(o.ReadOnly),
+		},
+	}
+	out, err := o.CommandRunner(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to run %s script %s", name, script)
+	}
+	out = strings.TrimSpace(out)
+	if out != "" {
+		log.Logger().Infof("%s script %s: %s", name, script, out)
+	}
+	return nil
+}
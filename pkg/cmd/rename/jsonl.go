@@ -0,0 +1,44 @@
+package rename
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// JSONLEntry the JSON Lines entry written for each file visited
+type JSONLEntry struct {
+	Path      string  `json:"path"`
+	Canonical string  `json:"canonical"`
+	Kind      string  `json:"kind"`
+	Name      string  `json:"name"`
+	Action    string  `json:"action"`
+	Error     *string `json:"error"`
+}
+
+// writeJSONL streams the JSON Lines entry of the file as soon as it has been processed
+func (o *Options) writeJSONL(r *FileResult) error {
+	entry := &JSONLEntry{
+		Path:   o.relativePath(r.Path),
+		Kind:   r.Kind,
+		Name:   r.Name,
+		Action: r.Action,
+	}
+	if r.Canonical != "" {
+		entry.Canonical = o.relativePath(r.Canonical)
+	}
+	if r.Error != nil {
+		msg := r.Error.Error()
+		entry.Error = &msg
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal JSON Lines entry for %s", r.Path)
+	}
+	_, err = fmt.Fprintln(o.jsonlOut, string(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to write JSON Lines entry for %s", r.Path)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package rename
+
+import (
+	"path"
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// kustomizationFiles the names of the kustomize files in a dir
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// renamedDirs returns the dirs containing the renamed files relative to the source dir
+func renamedDirs(renames map[string]string) map[string]bool {
+	answer := map[string]bool{}
+	for original := range renames {
+		answer[path.Dir(original)] = true
+	}
+	return answer
+}
+
+// updateKustomizeResources updates the resources of the kustomization file in the given dir, relative to the
+// source dir, which reference a renamed file. The referencing resource is added as a reference of the renamed files
+func (o *Options) updateKustomizeResources(kind, referencePath, dir string, renames map[string]string) error {
+	dir = filepath.Join(o.Dir, filepath.FromSlash(dir))
+	for _, name := range kustomizationFiles {
+		path := filepath.Join(dir, name)
+		exists, err := files.FileExists(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if file exists %s", path)
+		}
+		if !exists {
+			continue
+		}
+		node, err := yaml.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load file %s", path)
+		}
+		resources, err := node.Pipe(yaml.Lookup("resources"))
+		if err != nil {
+			return errors.Wrapf(err, "failed to find resources in %s", path)
+		}
+		if resources == nil || resources.YNode().Kind != yaml.SequenceNode {
+			return nil
+		}
+		modified := false
+		for _, value := range resources.YNode().Content {
+			if value.Kind != yaml.ScalarNode {
+				continue
+			}
+			canonical, newValue := o.renamedFileReference(dir, value.Value, renames)
+			if newValue == "" {
+				continue
+			}
+			log.Logger().Infof("updated kustomization %s resource %s => %s", o.relativePath(path), value.Value, newValue)
+			o.references = append(o.references, reference{Kind: kind, Path: referencePath, File: canonical})
+			value.Value = newValue
+			modified = true
+		}
+		if !modified {
+			return nil
+		}
+		err = yaml.WriteFile(node, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save file %s", path)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package rename
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// matchingLabels returns the labels of all the --label-file patterns matching the path relative to the directory
+func (o *Options) matchingLabels(path string) map[string]string {
+	rel := filepath.ToSlash(o.relativePath(path))
+	var patterns []string
+	for pattern := range o.fileLabels {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	answer := map[string]string{}
+	for _, pattern := range patterns {
+		matched, _ := filepath.Match(pattern, rel)
+		if !matched {
+			matched, _ = filepath.Match(pattern, filepath.Base(rel))
+		}
+		if matched {
+			for k, v := range o.fileLabels[pattern] {
+				answer[k] = v
+			}
+		}
+	}
+	return answer
+}
+
+// addLabels adds the matching labels from the --label-file to the given file
+func (o *Options) addLabels(sourcePath, path string) error {
+	labels := o.matchingLabels(sourcePath)
+	if len(labels) == 0 {
+		return nil
+	}
+	node, err := yaml.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+	for k, v := range labels {
+		err = node.PipeE(yaml.SetLabel(k, v))
+		if err != nil {
+			return errors.Wrapf(err, "failed to set label %s=%s on file %s", k, v, path)
+		}
+	}
+	err = yaml.WriteFile(node, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
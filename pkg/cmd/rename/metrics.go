@@ -0,0 +1,25 @@
+package rename
+
+import (
+	"fmt"
+	"time"
+)
+
+// writeMetrics writes the summary of the results in the Prometheus text format
+func (o *Options) writeMetrics(duration time.Duration) {
+	s := o.CreateSummary()
+	metrics := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"rename_files_total", "The number of YAML files processed", float64(s.Scanned)},
+		{"rename_files_renamed", "The number of YAML files renamed", float64(s.Renamed)},
+		{"rename_files_skipped", "The number of YAML files skipped", float64(s.Skipped)},
+		{"rename_files_errored", "The number of YAML files which could not be processed", float64(s.Errors)},
+		{"rename_duration_seconds", "The time taken to process the YAML files in seconds", duration.Seconds()},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(o.Out, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", m.name, m.help, m.name, m.name, m.value)
+	}
+}
@@ -0,0 +1,174 @@
+package rename
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// OutputFormatNames outputs the canonical name and original path of each file separated by a tab
+	OutputFormatNames = "names"
+
+	// OutputFormatPairs outputs each original path and its canonical name
+	OutputFormatPairs = "pairs"
+
+	// OutputFormatJSON outputs a JSON array of the canonical names
+	OutputFormatJSON = "json"
+)
+
+// DefaultFormat the --format template equivalent to the pairs output format
+const DefaultFormat = "{{ .From }} => {{ .To }}"
+
+// OutputFormats the supported values of --output-format
+var OutputFormats = []string{OutputFormatNames, OutputFormatPairs, OutputFormatJSON}
+
+// CanonicalName the computed canonical name of a file
+type CanonicalName struct {
+	Path      string `json:"path"`
+	Canonical string `json:"canonical"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// CreateCanonicalNames returns the canonical names computed for the files processed
+func (o *Options) CreateCanonicalNames() []CanonicalName {
+	answer := []CanonicalName{}
+	for _, r := range o.Results {
+		if r.Canonical == "" {
+			continue
+		}
+		answer = append(answer, CanonicalName{
+			Path:      o.outputPath(r.Path),
+			Canonical: o.outputPath(r.Canonical),
+			Kind:      r.Kind,
+			Name:      r.Name,
+		})
+	}
+	return answer
+}
+
+// writeOutput writes the computed canonical names using the --output-format
+func (o *Options) writeOutput() error {
+	names := o.CreateCanonicalNames()
+	switch o.OutputFormat {
+	case OutputFormatJSON:
+		data, err := json.MarshalIndent(names, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal canonical names")
+		}
+		fmt.Fprintln(o.Out, string(data))
+	case OutputFormatNames:
+		for _, n := range names {
+			fmt.Fprintf(o.Out, "%s\t%s\n", n.Canonical, n.Path)
+		}
+	default:
+		for _, n := range names {
+			fmt.Fprintf(o.Out, "%s => %s\n", n.Path, n.Canonical)
+		}
+	}
+	return nil
+}
+
+// writeKV writes each renamed file as an OLD_PATH='NEW_PATH' line which can be evaluated by a shell. The old path is
+// converted into a valid shell variable name and the new path is quoted. In read only mode the files which would be renamed are written
+func (o *Options) writeKV() {
+	for _, r := range o.Results {
+		if o.isRenamed(r) {
+			fmt.Fprintf(o.Out, "%s=%s\n", shellVariableName(o.outputPath(r.Path)), shellQuote(o.outputPath(r.Canonical)))
+		}
+	}
+}
+
+// writeRenamedOnly writes each renamed file as an original => canonical line. In read only mode the files which would be renamed are written
+func (o *Options) writeRenamedOnly() {
+	for _, r := range o.Results {
+		if o.isRenamed(r) {
+			fmt.Fprintf(o.Out, "%s => %s\n", o.outputPath(r.Path), o.outputPath(r.Canonical))
+		}
+	}
+}
+
+// isRenamed returns true if the file was renamed or in read only mode would have been renamed
+func (o *Options) isRenamed(r *FileResult) bool {
+	return r.Action == ActionRenamed || (o.ReadOnly && r.Action == ActionSkipped && r.Canonical != "" && r.Canonical != r.Path)
+}
+
+// shellVariableName converts the text into a valid shell variable name by replacing any invalid characters with an underscore
+func shellVariableName(text string) string {
+	buf := &strings.Builder{}
+	for i, c := range text {
+		switch {
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			buf.WriteRune(c)
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				buf.WriteRune('_')
+			}
+			buf.WriteRune(c)
+		default:
+			buf.WriteRune('_')
+		}
+	}
+	if buf.Len() == 0 {
+		return "_"
+	}
+	return buf.String()
+}
+
+// shellQuote quotes the text so that it is evaluated by a shell as a single literal word
+func shellQuote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
+
+// outputPath returns the path to output which is relative to the directory if --output-relative-paths is enabled
+func (o *Options) outputPath(path string) string {
+	if o.OutputRelativePaths {
+		return o.relativePath(path)
+	}
+	return path
+}
+
+// FormatEntry the values available to the --format template for each file
+type FormatEntry struct {
+	From   string
+	To     string
+	Kind   string
+	Name   string
+	Action string
+	Error  string
+}
+
+// writeFormatted writes a line for each file with a canonical name or an error using the --format template.
+// Lines which evaluate to an empty string are omitted
+func (o *Options) writeFormatted() error {
+	for _, r := range o.Results {
+		if r.Canonical == "" && r.Error == nil {
+			continue
+		}
+		entry := FormatEntry{
+			From:   o.outputPath(r.Path),
+			Kind:   r.Kind,
+			Name:   r.Name,
+			Action: r.Action,
+		}
+		if r.Canonical != "" {
+			entry.To = o.outputPath(r.Canonical)
+		}
+		if r.Error != nil {
+			entry.Error = r.Error.Error()
+		}
+		buf := &strings.Builder{}
+		err := o.formatTemplate.Execute(buf, entry)
+		if err != nil {
+			return errors.Wrapf(err, "failed to evaluate the --format template for %s", r.Path)
+		}
+		line := strings.TrimSuffix(buf.String(), "\n")
+		if line != "" {
+			fmt.Fprintln(o.Out, line)
+		}
+	}
+	return nil
+}
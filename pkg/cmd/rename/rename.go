@@ -2,14 +2,26 @@ package rename
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/jenkins-x/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -19,6 +31,9 @@ import (
 var (
 	splitLong = templates.LongDesc(`
 		Renames yaml files to use canonical file names based on the resource name and kind
+
+If a --filter-script is specified it is executed for every YAML file with the file path as its argument and only files for which the script exits with 0 are renamed. If the script cannot be run the command fails.
+Note that the script runs with the same permissions as this command so only use scripts you trust.
 `)
 
 	splitExample = templates.Examples(`
@@ -30,10 +45,136 @@ var (
 	resourcesSeparator = "---\n"
 )
 
+const (
+	// VersionFile the file name used to record the canonical naming scheme version used in a directory
+	VersionFile = ".rename-version"
+
+	// LatestVersion the latest version of the canonical naming scheme
+	LatestVersion = 1
+)
+
+const (
+	// ActionRenamed the file was renamed to its canonical name
+	ActionRenamed = "renamed"
+
+	// ActionSkipped the file was not renamed
+	ActionSkipped = "skipped"
+
+	// ActionError the file could not be processed
+	ActionError = "error"
+)
+
+// FileResult the result of processing a YAML file
+type FileResult struct {
+	Path      string
+	Canonical string
+	Kind      string
+	Name      string
+	Action    string
+	Duplicate string
+	Error     error
+	Duration  time.Duration
+}
+
 // Options the options for the command
 type Options struct {
-	Dir     string
-	Verbose bool
+	Dir                   string
+	OutputDir             string
+	NoCreateDir           bool
+	TargetVersion         int
+	FilterScript          string
+	PreHook               string
+	PostHook              string
+	BackupDir             string
+	Restore               bool
+	ReadOnly              bool
+	FollowGitSubmodules   bool
+	CompareContent        bool
+	CheckDuplicateContent bool
+	TargetKinds           []string
+	Depth                 int
+	RecursiveLimit        int
+	Recursive             bool
+	CheckGitTracked       bool
+	StrictYAML            bool
+	IgnoreErrors          bool
+	UpdateArgoCDApps      bool
+	UpdateHelmfile        bool
+	UpdateFluxGitRepos    bool
+	FluxDir               string
+	UpdateTerraformRefs   bool
+	TerraformDir          string
+	UpdateSkaffold        bool
+	SkaffoldFile          string
+	UpdateCompose         bool
+	TrimSuffix            bool
+	Separator             string
+	RegexReplace          []string
+	LabelFile             string
+	NoFollowSymlinks      bool
+	EmitGraph             bool
+	GraphFile             string
+	EmitGraphviz          string
+	ArgoCDAppDir          string
+	OutputFormat          string
+	Format                string
+	OutputKV              bool
+	OutputRelativePaths   bool
+	EmitRenamedOnly       bool
+	SummaryOnly           bool
+	EmitMetrics           bool
+	EmitNoop              bool
+	EmitTable             bool
+	ParseComments         bool
+	SkipManaged           string
+	IgnoreTemplateFiles   bool
+	IgnoreCRDs            bool
+	WarnNonStandardExts   bool
+	Strict                bool
+	Report                string
+	EmitCSV               string
+	OutputJSONL           string
+	ReportFormat          string
+	Out                   io.Writer
+	SummaryYAML           string
+	InverseMap            string
+	Trace                 bool
+	TraceFile             string
+	Verbose               bool
+	S3Source              string
+	S3Dest                string
+	AWSRegion             string
+	AWSProfile            string
+	S3Client              S3API
+	CommandRunner         cmdrunner.CommandRunner
+	SimulateError         func(path string) error
+	Results               []*FileResult
+	traceOut              io.Writer
+	jsonlOut              io.Writer
+	contentHashes         map[string]string
+	dirContentHashes      map[string]map[string]string
+	gitTracked            map[string]bool
+	nameReplacements      []nameReplacement
+	fileLabels            map[string]map[string]string
+	references            []reference
+	scheme                *namingScheme
+	formatTemplate        *template.Template
+	s3Source              *s3Location
+	s3Dest                *s3Location
+	s3TempDir             string
+	backupRenames         []BackupRename
+}
+
+// nameReplacement a regular expression replacement applied to resource names
+type nameReplacement struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// namingScheme the kind suffixes and separator used by a version of the canonical naming scheme
+type namingScheme struct {
+	KindSuffixes map[string]string
+	Separator    string
 }
 
 // NewCmdRename creates a command object for the command
@@ -51,61 +192,791 @@ func NewCmdRename() (*cobra.Command, *Options) {
 		},
 	}
 	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory to recursively look for the *.yaml or *.yml files")
+	cmd.Flags().StringVarP(&o.OutputDir, "output-dir", "o", "", "if specified the files are copied to this directory using their canonical names rather than being renamed in place")
+	cmd.Flags().BoolVarP(&o.NoCreateDir, "no-create-dir", "", false, "fails if the --output-dir does not exist rather than creating it")
+	cmd.Flags().IntVarP(&o.TargetVersion, "target-version", "", 0, fmt.Sprintf("the version of the canonical naming scheme to use. If specified the version is recorded in the %s file in the directory. Defaults to the latest version %d", VersionFile, LatestVersion))
+	cmd.Flags().BoolVarP(&o.ReadOnly, "read-only", "", false, "asserts the directory must never be modified, such as when it is a read only volume. The renames are only logged")
+	cmd.Flags().StringVarP(&o.FilterScript, "filter-script", "", "", "an optional script invoked with the path of each YAML file. Only files for which the script exits with 0 are renamed")
+	cmd.Flags().StringVarP(&o.S3Source, "s3-source", "", "", "an optional s3://bucket/prefix URL. If specified the YAML files are downloaded from S3, renamed and uploaded back to S3 rather than renaming the files in --dir")
+	cmd.Flags().StringVarP(&o.S3Dest, "s3-dest", "", "", "an optional s3://bucket/prefix URL the renamed YAML files are uploaded to. Defaults to --s3-source in which case the original objects are deleted")
+	cmd.Flags().StringVarP(&o.AWSRegion, "aws-region", "", "", "the AWS region of the S3 bucket. If not specified the standard AWS configuration is used")
+	cmd.Flags().StringVarP(&o.AWSProfile, "aws-profile", "", "", "the AWS profile used to access S3. If not specified the standard AWS configuration is used")
+	cmd.Flags().StringVarP(&o.PreHook, "pre-hook", "", "", "an optional script run once before the files are renamed. The RENAME_DIR, RENAME_COUNT and RENAME_DRY_RUN environment variables are passed to the script")
+	cmd.Flags().StringVarP(&o.PostHook, "post-hook", "", "", "an optional script run once after the files are renamed. The RENAME_DIR, RENAME_COUNT and RENAME_DRY_RUN environment variables are passed to the script")
+	cmd.Flags().StringVarP(&o.BackupDir, "backup-dir", "", "", "if specified the original files are copied into this directory before they are renamed along with a manifest of the renames used by --restore")
+	cmd.Flags().BoolVarP(&o.Restore, "restore", "", false, "restores the original files from the --backup-dir reversing the renames")
+	cmd.Flags().BoolVarP(&o.FollowGitSubmodules, "follow-gitsubmodules", "", false, "if enabled files inside git submodules are also renamed")
+	cmd.Flags().BoolVarP(&o.CompareContent, "compare-content", "", false, "if enabled files with identical content are reported as duplicates and a file is not renamed if the file at its canonical name has identical content")
+	cmd.Flags().BoolVarP(&o.CheckDuplicateContent, "check-duplicate-content", "", false, "if enabled a warning is logged for each file with identical content to another file in the same directory")
+	cmd.Flags().BoolVarP(&o.StrictYAML, "strict-yaml", "", false, "if enabled files containing duplicate keys, unknown anchors or tab indentation are rejected as errors")
+	cmd.Flags().BoolVarP(&o.IgnoreErrors, "ignore-errors", "", false, "if enabled files which fail to be processed are logged and skipped rather than failing the command")
+	cmd.Flags().BoolVarP(&o.EmitGraph, "emit-graph", "", false, "if enabled a DOT graph of the renamed files and the ArgoCD Applications updated to reference them is written")
+	cmd.Flags().StringVarP(&o.EmitGraphviz, "emit-graphviz", "", "", "if specified a DOT graph of the number of files of each kind is written to this file")
+	cmd.Flags().StringVarP(&o.GraphFile, "graph-file", "", "", "the file the --emit-graph DOT graph is written to. If not specified it is written to the console")
+	cmd.Flags().BoolVarP(&o.NoFollowSymlinks, "no-follow-symlinks", "", false, "if enabled symbolic links to YAML files are skipped. Symbolic links to directories are never followed")
+	cmd.Flags().StringVarP(&o.LabelFile, "label-file", "", "", "a YAML file mapping glob patterns of file paths to labels which are added to the matching files copied to the --output-dir")
+	cmd.Flags().StringArrayVarP(&o.RegexReplace, "regex-replace", "", nil, "a regular expression replacement of the form pattern=replacement applied to resource names before the kind suffix is added. Named groups can be referenced via ${name}. Multiple replacements are applied in order")
+	cmd.Flags().BoolVarP(&o.WarnNonStandardExts, "warn-non-standard-extensions", "", false, "if enabled a warning is logged for each file using the .yml extension rather than the standard .yaml extension. The extension is not renamed")
+	cmd.Flags().BoolVarP(&o.Strict, "strict", "", false, "if enabled the --warn-non-standard-extensions warnings are reported as errors")
+	cmd.Flags().BoolVarP(&o.IgnoreCRDs, "ignore-crds", "", false, "if enabled CustomResourceDefinition files are not renamed")
+	cmd.Flags().BoolVarP(&o.IgnoreTemplateFiles, "ignore-template-files", "", false, "if enabled files containing Go template expressions such as {{ .Values.name }} are skipped rather than failing to parse")
+	cmd.Flags().StringVarP(&o.SkipManaged, "skip-managed", "", "", "an annotation key such as jx.io/managed-by. If specified resources with this annotation are considered externally managed and are not renamed")
+	cmd.Flags().BoolVarP(&o.ParseComments, "parse-comments", "", false, "if enabled the kind and name of files without the standard kind and metadata.name fields are parsed from a first line comment of the form '# kind: Deployment, name: frontend'")
+	cmd.Flags().StringVarP(&o.Separator, "separator", "", "", "the separator between the resource name and the kind suffix such as '-' in cheese-svc.yaml. Defaults to the separator of the naming scheme. Equivalent to --suffix-separator")
+	cmd.Flags().StringVarP(&o.Separator, "suffix-separator", "", "", "the separator between the resource name and the kind suffix such as '-' in cheese-svc.yaml. Defaults to the separator of the naming scheme. Equivalent to --separator")
+	cmd.Flags().BoolVarP(&o.TrimSuffix, "trim-suffix", "", false, "if enabled the known kind suffix is removed from files which already have it, such as renaming frontend-deploy.yaml to frontend.yaml")
+	cmd.Flags().BoolVarP(&o.UpdateArgoCDApps, "update-argocd-apps", "", false, "if enabled the file names in the directory include of any ArgoCD Application whose spec.source.path is a dir containing renamed files are updated along with the resources of any kustomization file in the dir")
+	cmd.Flags().StringVarP(&o.ArgoCDAppDir, "argocd-app-dir", "", "", "the directory containing the ArgoCD Applications if different from --dir")
+	cmd.Flags().BoolVarP(&o.UpdateHelmfile, "update-helmfile", "", false, "if enabled any values file references in helmfile.yaml and helmfile.d/*.yaml which reference a renamed file are updated")
+	cmd.Flags().BoolVarP(&o.UpdateFluxGitRepos, "update-flux-git-repositories", "", false, "if enabled the resources of the kustomization file in the spec.path dir of any Flux Kustomization which contains renamed files are updated")
+	cmd.Flags().StringVarP(&o.FluxDir, "flux-dir", "", "", "the directory containing the Flux resources to update. Defaults to --dir")
+	cmd.Flags().BoolVarP(&o.UpdateTerraformRefs, "update-terraform-refs", "", false, "if enabled any file() or templatefile() reference to a renamed file in the Terraform files is updated")
+	cmd.Flags().StringVarP(&o.TerraformDir, "terraform-dir", "", "", "the directory containing the Terraform files to update. Defaults to --dir")
+	cmd.Flags().BoolVarP(&o.UpdateSkaffold, "update-skaffold", "", false, "if enabled any manifests.rawYaml or deploy.kubectl.manifests reference to a renamed file in the skaffold configuration is updated")
+	cmd.Flags().StringVarP(&o.SkaffoldFile, "skaffold-file", "", "", "the skaffold configuration file to update. Defaults to skaffold.yaml in --dir")
+	cmd.Flags().BoolVarP(&o.UpdateCompose, "update-compose", "", false, "if enabled any service volume, config or secret reference to a renamed file in the docker-compose.yml or docker-compose.yaml files in --dir is updated")
+	cmd.Flags().BoolVarP(&o.CheckGitTracked, "check-git-tracked", "", false, "if enabled only files tracked by git are renamed. Untracked files are skipped")
+	cmd.Flags().IntVarP(&o.Depth, "depth", "", -1, "the maximum depth of directories below --dir to look for files. 0 only processes --dir itself, 1 includes its immediate sub directories and so on. Negative values are unlimited")
+	cmd.Flags().IntVarP(&o.RecursiveLimit, "recursive-limit", "", -1, "an alias for --depth")
+	cmd.Flags().BoolVarP(&o.Recursive, "recursive", "", true, "if disabled only the files in --dir itself are processed. Cannot be disabled when --depth or --recursive-limit is specified")
+	cmd.Flags().StringArrayVarP(&o.TargetKinds, "target-kind", "", nil, "if specified only resources of these kinds are renamed. The kind is matched case insensitively")
+	cmd.Flags().StringVarP(&o.OutputFormat, "output-format", "", "", fmt.Sprintf("if specified the canonical names are only computed and output rather than renaming any files. Supported values: %s", strings.Join(OutputFormats, ", ")))
+	cmd.Flags().BoolVarP(&o.OutputRelativePaths, "output-relative-paths", "", false, "if enabled the paths output by --output-format, --output-kv and --emit-renamed-only are relative to --dir rather than absolute")
+	cmd.Flags().StringVarP(&o.Format, "format", "", "", fmt.Sprintf("an optional Go template used to output a line for each file with a canonical name or an error. The .From, .To, .Kind, .Name, .Action and .Error values are available. The pairs output format is equivalent to '%s'", DefaultFormat))
+	cmd.Flags().BoolVarP(&o.OutputKV, "output-kv", "", false, "if enabled each renamed file is output as an OLD_PATH='NEW_PATH' line for evaluating in a shell. The old path is converted into a valid shell variable name")
+	cmd.Flags().BoolVarP(&o.EmitRenamedOnly, "emit-renamed-only", "", false, "if enabled all log output is suppressed and only the renamed files are output, one per line. Combine with --read-only to preview the renames")
+	cmd.Flags().BoolVarP(&o.EmitNoop, "emit-noop", "", false, "if enabled a message is logged for each file which already has its canonical name")
+	cmd.Flags().BoolVarP(&o.EmitTable, "emit-table", "", false, "if enabled a table of the original and canonical names, kind, name and action of each file is output sorted by action. Long paths are truncated to fit the COLUMNS terminal width")
+	cmd.Flags().StringVarP(&o.Report, "report", "", "", "if specified a report of the original and canonical names, kind, name and action of each file is written to this file")
+	cmd.Flags().StringVarP(&o.ReportFormat, "report-format", "", ReportFormatJSON, fmt.Sprintf("the format of the --report file. Supported values: %s", strings.Join(ReportFormats, ", ")))
+	cmd.Flags().BoolVarP(&o.SummaryOnly, "summary-only", "", false, "if enabled the per file log output is suppressed and only the number of files renamed, skipped and failed is output. Errors are still logged")
+	cmd.Flags().BoolVarP(&o.EmitMetrics, "emit-metrics", "", false, "if enabled the number of files processed, renamed, skipped and failed and the duration are output in the Prometheus text format")
+	cmd.Flags().StringVarP(&o.EmitCSV, "emit-csv", "", "", "if specified a CSV file with the columns original, canonical, kind, name, action and error is written for each file processed. Combine with --read-only to preview the renames")
+	cmd.Flags().StringVarP(&o.OutputJSONL, "output-jsonl", "", "", "if specified a JSON Lines entry with the path, canonical name, kind, name, action and error is written to this file for each file visited as it is processed")
+	cmd.Flags().StringVarP(&o.SummaryYAML, "summary-yaml", "", "", "if specified a machine readable YAML summary of the files scanned, renamed, skipped and failed is written to this file")
+	cmd.Flags().StringVarP(&o.InverseMap, "emit-inverse-map", "", "", "if specified a JSON file is written mapping each canonical file name to its original file name")
+	cmd.Flags().BoolVarP(&o.Trace, "trace", "", false, "if enabled a JSON Lines trace entry is written to stderr for each file processed")
+	cmd.Flags().StringVarP(&o.TraceFile, "trace-file", "", "", "if specified the JSON Lines trace entries are written to this file rather than stderr")
+
 	return cmd, o
 }
 
+// Validate validates the options and resolves the naming scheme
+func (o *Options) Validate() error {
+	if o.Restore && o.BackupDir == "" {
+		return options.MissingOption("backup-dir")
+	}
+	if o.Restore && o.ReadOnly {
+		return options.InvalidOptionf("restore", o.Restore, "it cannot be combined with --read-only")
+	}
+	if o.OutputDir != "" && o.ReadOnly {
+		return options.InvalidOptionf("output-dir", o.OutputDir, "it cannot be combined with --read-only")
+	}
+	if o.S3Dest != "" && o.S3Source == "" {
+		return options.MissingOption("s3-source")
+	}
+	if o.S3Source != "" && o.OutputDir != "" {
+		return options.InvalidOptionf("output-dir", o.OutputDir, "it cannot be combined with --s3-source")
+	}
+	if o.FilterScript != "" {
+		_, err := exec.LookPath(o.FilterScript)
+		if err != nil {
+			return options.InvalidOptionf("filter-script", o.FilterScript, "the script could not be found: %s", err.Error())
+		}
+	}
+
+	version := o.TargetVersion
+	if version == 0 {
+		version = LatestVersion
+	}
+	o.scheme = namingSchemes[version]
+	if o.scheme == nil {
+		return errors.Errorf("unsupported --target-version %d. The latest supported version is %d", version, LatestVersion)
+	}
+	if o.Separator != "" && o.Separator != o.scheme.Separator {
+		scheme := *o.scheme
+		scheme.Separator = o.Separator
+		o.scheme = &scheme
+	}
+
+	path := filepath.Join(o.Dir, VersionFile)
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if exists {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load file %s", path)
+		}
+		text := strings.TrimSpace(string(data))
+		previous, err := strconv.Atoi(text)
+		if err != nil {
+			log.Logger().Warnf("ignoring invalid naming scheme version %s in file %s", text, path)
+		} else if previous != version {
+			log.Logger().Warnf("the files in %s were renamed using naming scheme version %d but now using version %d", o.Dir, previous, version)
+		}
+	}
+	if o.OutputDir != "" {
+		exists, err := files.DirExists(o.OutputDir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if dir exists %s", o.OutputDir)
+		}
+		if !exists {
+			if o.NoCreateDir {
+				return errors.Errorf("the output dir %s does not exist", o.OutputDir)
+			}
+			err = os.MkdirAll(o.OutputDir, files.DefaultDirWritePermissions)
+			if err != nil {
+				return errors.Wrapf(err, "failed to create dir %s", o.OutputDir)
+			}
+		}
+	}
+	if o.LabelFile != "" {
+		if o.OutputDir == "" {
+			return options.MissingOption("output-dir")
+		}
+		o.fileLabels = map[string]map[string]string{}
+		err = yamls.LoadFile(o.LabelFile, &o.fileLabels)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load label file %s", o.LabelFile)
+		}
+	}
+	o.nameReplacements = nil
+	for _, text := range o.RegexReplace {
+		values := strings.SplitN(text, "=", 2)
+		if len(values) != 2 || values[0] == "" {
+			return options.InvalidOptionf("regex-replace", text, "should be of the form pattern=replacement")
+		}
+		re, err := regexp.Compile(values[0])
+		if err != nil {
+			return options.InvalidOptionf("regex-replace", text, "invalid regular expression: %s", err.Error())
+		}
+		o.nameReplacements = append(o.nameReplacements, nameReplacement{Pattern: re, Replacement: values[1]})
+	}
+	if o.OutputFormat != "" && stringhelpers.StringArrayIndex(OutputFormats, o.OutputFormat) < 0 {
+		return options.InvalidOption("output-format", o.OutputFormat, OutputFormats)
+	}
+	if o.Format != "" {
+		if o.OutputFormat != "" {
+			return options.InvalidOptionf("format", o.Format, "it cannot be combined with --output-format")
+		}
+		var err error
+		o.formatTemplate, err = template.New("format").Parse(o.Format)
+		if err != nil {
+			return options.InvalidOptionf("format", o.Format, "it is not a valid Go template: %s", err.Error())
+		}
+	}
+	if o.ReportFormat == "" {
+		o.ReportFormat = ReportFormatJSON
+	}
+	if stringhelpers.StringArrayIndex(ReportFormats, o.ReportFormat) < 0 {
+		return options.InvalidOption("report-format", o.ReportFormat, ReportFormats)
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.QuietCommandRunner
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if o.RecursiveLimit >= 0 {
+		if o.Depth >= 0 && o.Depth != o.RecursiveLimit {
+			return options.InvalidOptionf("recursive-limit", o.RecursiveLimit, "it is an alias for --depth which is specified as %d", o.Depth)
+		}
+		o.Depth = o.RecursiveLimit
+	}
+	if !o.Recursive {
+		if o.Depth >= 0 {
+			return options.InvalidOptionf("recursive", o.Recursive, "--depth and --recursive-limit cannot be used when --recursive is disabled")
+		}
+		o.Depth = 0
+	}
+	if o.SummaryOnly && o.EmitRenamedOnly {
+		return options.InvalidOptionf("summary-only", o.SummaryOnly, "it cannot be combined with --emit-renamed-only")
+	}
+	return nil
+}
+
 // Run implements the command
 func (o *Options) Run() error {
-	err := filepath.Walk(o.Dir, func(path string, info os.FileInfo, err error) error {
-		if info == nil || info.IsDir() {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	if o.S3Source != "" {
+		err = o.downloadS3Source()
+		if o.s3TempDir != "" {
+			defer os.RemoveAll(o.s3TempDir)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to download the YAML files from %s", o.S3Source)
+		}
+	}
+
+	if o.EmitRenamedOnly {
+		level := log.GetLevel()
+		err = log.SetLevel("fatal")
+		if err != nil {
+			return errors.Wrapf(err, "failed to suppress logging")
+		}
+		defer log.SetLevel(level) //nolint:errcheck
+	}
+	if o.SummaryOnly {
+		level := log.GetLevel()
+		err = log.SetLevel("error")
+		if err != nil {
+			return errors.Wrapf(err, "failed to suppress logging")
+		}
+		defer log.SetLevel(level) //nolint:errcheck
+	}
+
+	if o.Restore {
+		return o.restoreBackup()
+	}
+
+	if o.Trace || o.TraceFile != "" {
+		o.traceOut = os.Stderr
+		if o.TraceFile != "" {
+			f, err := os.Create(o.TraceFile)
+			if err != nil {
+				return errors.Wrapf(err, "failed to create trace file %s", o.TraceFile)
+			}
+			defer f.Close()
+			o.traceOut = f
+		}
+	}
+
+	if o.OutputJSONL != "" {
+		f, err := os.Create(o.OutputJSONL)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create JSON Lines file %s", o.OutputJSONL)
+		}
+		defer f.Close()
+		o.jsonlOut = f
+	}
+
+	if o.PreHook != "" {
+		err = o.runHook("pre-hook", o.PreHook)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.CheckGitTracked {
+		err = o.loadGitTrackedFiles()
+		if err != nil {
+			return errors.Wrapf(err, "failed to find the files tracked by git in dir %s", o.Dir)
+		}
+	}
+
+	start := time.Now()
+	err = filepath.Walk(o.Dir, func(path string, info os.FileInfo, err error) error {
+		if info == nil {
 			return nil
 		}
-		if !strings.HasSuffix(path, ".yaml") && !strings.HasSuffix(path, ".yml") {
+		if info.IsDir() {
+			if o.OutputDir != "" && filepath.Clean(path) == filepath.Clean(o.OutputDir) {
+				return filepath.SkipDir
+			}
+			if o.BackupDir != "" && filepath.Clean(path) == filepath.Clean(o.BackupDir) {
+				return filepath.SkipDir
+			}
+			if o.Depth >= 0 && o.dirDepth(path) > o.Depth {
+				log.Logger().Debugf("ignoring dir %s and any deeper dirs as the depth limit is %d", path, o.Depth)
+				return filepath.SkipDir
+			}
+			if !o.FollowGitSubmodules && filepath.Clean(path) != filepath.Clean(o.Dir) {
+				submodule, err := files.FileExists(filepath.Join(path, ".git"))
+				if err != nil {
+					return errors.Wrapf(err, "failed to check for git submodule in %s", path)
+				}
+				if submodule {
+					log.Logger().Debugf("ignoring git submodule %s", path)
+					return filepath.SkipDir
+				}
+			}
 			return nil
 		}
+		if !isYAMLFile(path) {
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if o.NoFollowSymlinks {
+				log.Logger().Debugf("ignoring symbolic link %s", path)
+				return nil
+			}
+			target, err := os.Stat(path)
+			if err != nil {
+				log.Logger().Warnf("ignoring symbolic link %s as it cannot be resolved: %s", path, err.Error())
+				return nil
+			}
+			if target.IsDir() {
+				return nil
+			}
+		}
+		return o.renameFile(path)
+	})
+	duration := time.Since(start)
 
-		node, err := yaml.ReadFile(path)
+	if o.BackupDir != "" {
+		backupErr := o.saveBackupManifest()
+		if backupErr != nil {
+			return backupErr
+		}
+	}
+
+	if o.OutputFormat != "" {
+		outputErr := o.writeOutput()
+		if outputErr != nil {
+			return outputErr
+		}
+	}
+	if o.formatTemplate != nil {
+		formatErr := o.writeFormatted()
+		if formatErr != nil {
+			return formatErr
+		}
+	}
+	if o.OutputKV {
+		o.writeKV()
+	}
+	if o.EmitRenamedOnly {
+		o.writeRenamedOnly()
+	}
+	if o.EmitTable {
+		o.writeTable()
+	}
+	if o.SummaryOnly {
+		o.writeSummary()
+	}
+	if o.EmitMetrics {
+		o.writeMetrics(duration)
+	}
+	if o.Report != "" {
+		reportErr := o.writeReport()
+		if reportErr != nil {
+			return reportErr
+		}
+	}
+	if o.EmitCSV != "" {
+		csvErr := o.writeCSV()
+		if csvErr != nil {
+			return csvErr
+		}
+	}
+	if o.SummaryYAML != "" {
+		summaryErr := o.writeSummaryYAML()
+		if summaryErr != nil {
+			return summaryErr
+		}
+	}
+	if o.InverseMap != "" {
+		mapErr := o.writeInverseMap()
+		if mapErr != nil {
+			return mapErr
+		}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to rename YAML files in dir %s", o.Dir)
+	}
+
+	if o.UpdateArgoCDApps && o.OutputDir == "" {
+		err = o.updateArgoCDApps()
+		if err != nil {
+			return err
+		}
+	}
+	if o.UpdateHelmfile && o.OutputDir == "" {
+		err = o.updateHelmfiles()
+		if err != nil {
+			return err
+		}
+	}
+	if o.UpdateFluxGitRepos && o.OutputDir == "" {
+		err = o.updateFluxResources()
+		if err != nil {
+			return err
+		}
+	}
+	if o.UpdateTerraformRefs && o.OutputDir == "" {
+		err = o.updateTerraformRefs()
+		if err != nil {
+			return err
+		}
+	}
+	if o.UpdateSkaffold && o.OutputDir == "" {
+		err = o.updateSkaffold()
+		if err != nil {
+			return err
+		}
+	}
+	if o.UpdateCompose && o.OutputDir == "" {
+		err = o.updateComposeFiles()
+		if err != nil {
+			return err
+		}
+	}
+	if o.EmitGraph {
+		err = o.writeGraph()
+		if err != nil {
+			return err
+		}
+	}
+	if o.EmitGraphviz != "" {
+		err = o.writeKindGraph()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.TargetVersion > 0 && !o.ReadOnly && o.OutputFormat == "" {
+		path := filepath.Join(o.Dir, VersionFile)
+		err = ioutil.WriteFile(path, []byte(strconv.Itoa(o.TargetVersion)+"\n"), files.DefaultFileWritePermissions)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save file %s", path)
+		}
+	}
+
+	if o.S3Source != "" && !o.ReadOnly {
+		err = o.uploadS3Results()
+		if err != nil {
+			return errors.Wrapf(err, "failed to upload the renamed YAML files")
+		}
+	}
+
+	if o.PostHook != "" {
+		err = o.runHook("post-hook", o.PostHook)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Options) renameFile(path string) error {
+	start := time.Now()
+	r := &FileResult{Path: path}
+	err := o.processFile(r)
+	if err != nil {
+		r.Action = ActionError
+		r.Error = err
+	}
+	r.Duration = time.Since(start)
+	o.Results = append(o.Results, r)
+
+	if o.traceOut != nil {
+		traceErr := o.writeTrace(r)
+		if traceErr != nil {
+			return traceErr
+		}
+	}
+	if o.jsonlOut != nil {
+		jsonlErr := o.writeJSONL(r)
+		if jsonlErr != nil {
+			return jsonlErr
+		}
+	}
+	if err != nil && o.IgnoreErrors {
+		log.Logger().Errorf("ignoring file %s: %s", path, err.Error())
+		return nil
+	}
+	return err
+}
+
+func (o *Options) processFile(r *FileResult) error {
+	path := r.Path
+	r.Action = ActionSkipped
+	if o.FilterScript != "" {
+		c := &cmdrunner.Command{
+			Name: o.FilterScript,
+			Args: []string{path},
+		}
+		_, err := o.CommandRunner(c)
+		if err != nil {
+			if _, ok := errors.Cause(err).(*exec.ExitError); !ok {
+				return errors.Wrapf(err, "failed to run filter script %s on file %s", o.FilterScript, path)
+			}
+			log.Logger().Debugf("filter script %s excluded file %s: %s", o.FilterScript, path, err.Error())
+			return nil
+		}
+	}
+
+	if o.gitTracked != nil && !o.gitTracked[filepath.Clean(path)] {
+		log.Logger().Debugf("ignoring file %s as it is not tracked by git", path)
+		return nil
+	}
+
+	if o.WarnNonStandardExts && filepath.Ext(path) == ".yml" {
+		if o.Strict {
+			return errors.Errorf("file %s uses the non standard .yml extension rather than .yaml", path)
+		}
+		log.Logger().Warnf("file %s uses the non standard .yml extension rather than .yaml", path)
+	}
+
+	if o.IgnoreTemplateFiles {
+		data, err := ioutil.ReadFile(path)
 		if err != nil {
 			return errors.Wrapf(err, "failed to load file %s", path)
 		}
+		if strings.Contains(string(data), "{{") {
+			log.Logger().Infof("ignoring file %s as it is a template", path)
+			return nil
+		}
+	}
 
-		name := kyamls.GetName(node, path)
-		if name == "" {
-			log.Logger().Warnf("no name for file %s so ignoring", path)
+	if o.StrictYAML {
+		err := checkStrictYAML(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	node, err := yaml.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+
+	if o.SkipManaged != "" {
+		managedBy := kyamls.GetStringField(node, path, "metadata", "annotations", o.SkipManaged)
+		if managedBy != "" {
+			log.Logger().Debugf("ignoring file %s as it is managed by %s", path, managedBy)
 			return nil
 		}
+	}
 
-		kind := kyamls.GetKind(node, path)
-		apiVersion := kyamls.GetAPIVersion(node, path)
+	o.resolveCanonicalPath(node, r)
+	if !o.matchesTargetKind(r.Kind) {
+		log.Logger().Debugf("ignoring file %s as kind %s is not a target kind", path, r.Kind)
+		return nil
+	}
+	if o.IgnoreCRDs && kyamls.IsCustomResourceDefinition(r.Kind) {
+		log.Logger().Debugf("ignoring CustomResourceDefinition file %s", path)
+		return nil
+	}
+	newPath := r.Canonical
+	if newPath == "" {
+		log.Logger().Warnf("no name for file %s so ignoring", path)
+		return nil
+	}
+	if o.TrimSuffix {
+		newPath = o.trimSuffixPath(path, r.Kind)
+		r.Canonical = newPath
+	}
+	if o.CheckDuplicateContent {
+		err = o.checkDuplicateInDir(r)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check for duplicate content of %s", path)
+		}
+	}
+	if o.OutputFormat != "" {
+		return nil
+	}
 
-		dir, file := filepath.Split(path)
-		ext := filepath.Ext(path)
+	if o.CompareContent {
+		duplicate, err := o.checkDuplicateContent(r)
+		if err != nil {
+			return errors.Wrapf(err, "failed to compare content of %s", path)
+		}
+		if duplicate {
+			return nil
+		}
+	}
 
-		cn := o.canonicalName(apiVersion, kind, name)
+	if o.SimulateError != nil {
+		err := o.SimulateError(path)
+		if err != nil {
+			return err
+		}
+	}
 
-		newFile := cn + ext
-		newPath := filepath.Join(dir, newFile)
+	if o.OutputDir != "" {
+		return o.copyToOutputDir(r)
+	}
 
-		if newPath != path {
-			if o.Verbose {
-				log.Logger().Infof("renaming %s => %s", file, newFile)
-			} else {
-				log.Logger().Debugf("renaming %s => %s", file, newFile)
+	if newPath != path {
+		file := filepath.Base(path)
+		newFile := filepath.Base(newPath)
+		if o.ReadOnly {
+			log.Logger().Infof("read only mode so not renaming %s => %s", file, newFile)
+			return nil
+		}
+		if o.Verbose {
+			log.Logger().Infof("renaming %s => %s", file, newFile)
+		} else {
+			log.Logger().Debugf("renaming %s => %s", file, newFile)
+		}
+		if o.BackupDir != "" {
+			err = o.backupFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to backup file %s", path)
 			}
-			err = os.Rename(path, newPath)
+		}
+		err = os.Rename(path, newPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to rename %s to %s", file, newFile)
+		}
+		r.Action = ActionRenamed
+		if o.BackupDir != "" {
+			err = o.recordBackupRename(path, newPath)
 			if err != nil {
-				return errors.Wrapf(err, "failed to rename %s to %s", file, newFile)
+				return err
 			}
+		}
+	} else if o.EmitNoop {
+		log.Logger().Infof("already canonical: %s", o.relativePath(path))
+	}
+	return nil
+}
 
+// loadGitTrackedFiles finds the files in the directory which are tracked by git
+func (o *Options) loadGitTrackedFiles() error {
+	c := &cmdrunner.Command{
+		Dir:  o.Dir,
+		Name: "git",
+		Args: []string{"ls-files", "-z"},
+	}
+	text, err := o.CommandRunner(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to run %s", c.CLI())
+	}
+	o.gitTracked = map[string]bool{}
+	for _, name := range strings.Split(text, "\x00") {
+		if name != "" {
+			o.gitTracked[filepath.Join(o.Dir, name)] = true
 		}
-		return nil
-	})
+	}
+	return nil
+}
+
+// trimSuffixPath returns the path without its kind suffix if the file name ends with the known suffix of the given kind
+func (o *Options) trimSuffixPath(path, kind string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	idx := strings.LastIndex(base, o.scheme.Separator)
+	if idx <= 0 {
+		return path
+	}
+	suffix := base[idx+len(o.scheme.Separator):]
+	if suffix == "ksvc" {
+		suffix = "svc"
+	}
+	suffixKinds := map[string]string{}
+	for k, v := range o.scheme.KindSuffixes {
+		suffixKinds[v] = k
+	}
+	if suffixKinds[suffix] != strings.ToLower(kind) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(path), base[:idx]+ext)
+}
+
+// dirDepth returns the depth of the given directory below --dir
+func (o *Options) dirDepth(path string) int {
+	rel, err := filepath.Rel(o.Dir, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return len(strings.Split(rel, string(os.PathSeparator)))
+}
+
+// matchesTargetKind returns true if no --target-kind is specified or the kind matches one of them
+func (o *Options) matchesTargetKind(kind string) bool {
+	if len(o.TargetKinds) == 0 {
+		return true
+	}
+	for _, k := range o.TargetKinds {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyToOutputDir copies the file to its canonical name in the output dir
+func (o *Options) copyToOutputDir(r *FileResult) error {
+	rel, err := filepath.Rel(o.Dir, r.Canonical)
 	if err != nil {
-		return errors.Wrapf(err, "failed to rename YAML files in dir %s", o.Dir)
+		return errors.Wrapf(err, "failed to find relative path of %s in dir %s", r.Canonical, o.Dir)
+	}
+	newPath := filepath.Join(o.OutputDir, rel)
+	err = os.MkdirAll(filepath.Dir(newPath), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", filepath.Dir(newPath))
+	}
+	err = files.CopyFile(r.Path, newPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy %s to %s", r.Path, newPath)
 	}
+	if o.LabelFile != "" {
+		err = o.addLabels(r.Path, newPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add labels to %s", newPath)
+		}
+	}
+	log.Logger().Debugf("copied %s => %s", r.Path, newPath)
+	if r.Canonical != r.Path {
+		r.Action = ActionRenamed
+	}
+	r.Canonical = newPath
 	return nil
 }
 
+// canonicalPath returns the canonical path of the given file or an empty string if the resource has no name
+func (o *Options) canonicalPath(node *yaml.RNode, path string) string {
+	r := &FileResult{Path: path}
+	o.resolveCanonicalPath(node, r)
+	return r.Canonical
+}
+
+// resolveCanonicalPath populates the kind, name and canonical path of the result from the resource
+func (o *Options) resolveCanonicalPath(node *yaml.RNode, r *FileResult) {
+	path := r.Path
+	r.Kind = kyamls.GetKind(node, path)
+	r.Name = kyamls.GetName(node, path)
+	apiVersion := kyamls.GetAPIVersion(node, path)
+	if o.ParseComments && (r.Kind == "" || r.Name == "") {
+		metadata, err := parseCommentMetadata(path)
+		if err != nil {
+			log.Logger().Warnf("failed to parse the comment metadata of %s: %s", path, err.Error())
+		}
+		if r.Kind == "" {
+			r.Kind = metadata["kind"]
+		}
+		if r.Name == "" {
+			r.Name = metadata["name"]
+		}
+		if apiVersion == "" {
+			apiVersion = metadata["apiversion"]
+		}
+	}
+	if r.Name == "" {
+		return
+	}
+
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	r.Canonical = filepath.Join(dir, o.canonicalName(apiVersion, r.Kind, r.Name)+ext)
+}
+
+// relativePath returns the path relative to the output dir or the source dir if possible
+func (o *Options) relativePath(path string) string {
+	for _, dir := range []string{o.OutputDir, o.Dir} {
+		if dir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+func isYAMLFile(path string) bool {
+	return strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+}
+
 var (
 	kindSuffixes = map[string]string{
 		"clusterrolebinding":             "crb",
@@ -119,11 +990,22 @@ var (
 		"serviceaccount":                 "sa",
 		"validatingwebhookconfiguration": "valwebhookcfg",
 	}
+
+	// namingSchemes the canonical naming schemes indexed by version
+	namingSchemes = map[int]*namingScheme{
+		1: {
+			KindSuffixes: kindSuffixes,
+			Separator:    "-",
+		},
+	}
 )
 
 func (o *Options) canonicalName(apiVersion, kind, name string) string {
+	for _, r := range o.nameReplacements {
+		name = r.Pattern.ReplaceAllString(name, r.Replacement)
+	}
 	lk := strings.ToLower(kind)
-	suffix := kindSuffixes[lk]
+	suffix := o.scheme.KindSuffixes[lk]
 	if suffix == "svc" && strings.Contains(apiVersion, "knative") {
 		suffix = "ksvc"
 	}
@@ -133,5 +1015,5 @@ func (o *Options) canonicalName(apiVersion, kind, name string) string {
 	if kind == "" {
 		return name
 	}
-	return name + "-" + suffix
+	return name + o.scheme.Separator + suffix
 }
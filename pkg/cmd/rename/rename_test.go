@@ -1,14 +1,28 @@
 package rename_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jenkins-x/jx-gitops/pkg/cmd/rename"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestRenameYamlFiles(t *testing.T) {
@@ -40,3 +54,1599 @@ func TestRenameYamlFiles(t *testing.T) {
 		assert.FileExists(t, filepath.Join(tmpDir, f))
 	}
 }
+
+func TestRenameTargetVersion(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.TargetVersion = rename.LatestVersion
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	versionFile := filepath.Join(tmpDir, rename.VersionFile)
+	require.FileExists(t, versionFile)
+	data, err := ioutil.ReadFile(versionFile)
+	require.NoError(t, err, "failed to load file %s", versionFile)
+	assert.Equal(t, "1\n", string(data), "contents of %s", versionFile)
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.TargetVersion = 1000
+	err = o.Run()
+	require.Error(t, err, "should have failed for an unsupported version")
+}
+
+func TestRenameFilterScript(t *testing.T) {
+	tmpDir := copyTestData(t)
+	scriptDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	script := filepath.Join(scriptDir, "my-filter.sh")
+	err = ioutil.WriteFile(script, []byte("#!/bin/sh\ncase \"$1\" in *resource100.yaml) exit 1;; esac\n"), 0700)
+	require.NoError(t, err, "failed to save file %s", script)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.FilterScript = script
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+}
+
+func TestRenameFilterScriptErrors(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.FilterScript = filepath.Join(tmpDir, "does-not-exist.sh")
+	err := o.Run()
+	require.Error(t, err, "should have failed for a missing filter script")
+
+	scriptDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	script := filepath.Join(scriptDir, "my-filter.sh")
+	err = ioutil.WriteFile(script, []byte("#!/bin/sh\n"), 0700)
+	require.NoError(t, err, "failed to save file %s", script)
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.FilterScript = script
+	o.CommandRunner = func(c *cmdrunner.Command) (string, error) {
+		return "", errors.Errorf("permission denied")
+	}
+	err = o.Run()
+	require.Error(t, err, "should have failed when the filter script cannot be run")
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"))
+}
+
+func TestRenameHooks(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	var hooks []*cmdrunner.Command
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.PreHook = "pre.sh"
+	o.PostHook = "post.sh"
+	o.CommandRunner = func(c *cmdrunner.Command) (string, error) {
+		hooks = append(hooks, c)
+		if c.Name == "pre.sh" {
+			assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"), "should run the pre hook before renaming")
+		}
+		return "", nil
+	}
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	require.Len(t, hooks, 2, "hooks run")
+	assert.Equal(t, "pre.sh", hooks[0].Name, "pre hook")
+	assert.Equal(t, map[string]string{"RENAME_DIR": tmpDir, "RENAME_COUNT": "0", "RENAME_DRY_RUN": "false"}, hooks[0].Env, "pre hook env")
+	assert.Equal(t, "post.sh", hooks[1].Name, "post hook")
+	assert.Equal(t, strconv.Itoa(o.CreateSummary().Renamed), hooks[1].Env["RENAME_COUNT"], "post hook env")
+	assert.NotEqual(t, "0", hooks[1].Env["RENAME_COUNT"], "post hook env")
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.PreHook = "pre.sh"
+	o.CommandRunner = func(c *cmdrunner.Command) (string, error) {
+		return "", errors.Errorf("failed")
+	}
+	err = o.Run()
+	require.Error(t, err, "should fail when the pre hook fails")
+}
+
+func TestRenameBackupAndRestore(t *testing.T) {
+	tmpDir := copyTestData(t)
+	backupDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.BackupDir = backupDir
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "resource100.yaml"))
+	assert.FileExists(t, filepath.Join(backupDir, "resource100.yaml"))
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.BackupDir = backupDir
+	o.Restore = true
+	err = o.Run()
+	require.NoError(t, err, "failed to restore in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+}
+
+func TestRenameRestoreUsesBackupManifest(t *testing.T) {
+	tmpDir := copyTestData(t)
+	backupDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.BackupDir = backupDir
+	o.Separator = "_"
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese_svc.yaml"))
+
+	manifest := &rename.BackupManifest{}
+	err = yamls.LoadFile(filepath.Join(backupDir, rename.BackupManifestFile), manifest)
+	require.NoError(t, err, "failed to load the backup manifest")
+	assert.Contains(t, manifest.Renames, rename.BackupRename{From: "resource100.yaml", To: "cheese_svc.yaml"}, "backup manifest")
+
+	// the restore uses the recorded renames rather than the naming options of the restore
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.BackupDir = backupDir
+	o.Restore = true
+	err = o.Run()
+	require.NoError(t, err, "failed to restore in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "cheese_svc.yaml"))
+}
+
+func TestRenameReadOnlyInvalidOptions(t *testing.T) {
+	testCases := map[string][]string{
+		"restore":    {"--read-only", "--restore", "--backup-dir", "backup"},
+		"output-dir": {"--read-only", "--output-dir", "out"},
+	}
+	for name, args := range testCases {
+		tmpDir := copyTestData(t)
+
+		cmd, o := rename.NewCmdRename()
+		err := cmd.Flags().Parse(args)
+		require.NoError(t, err, "failed to parse flags %v", args)
+		o.Dir = tmpDir
+		err = o.Validate()
+		require.Error(t, err, "should not allow --read-only with --%s", name)
+
+		assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"), "should not modify the dir with --%s", name)
+		assert.NoDirExists(t, filepath.Join(tmpDir, "out"), "should not create the output dir with --%s", name)
+	}
+}
+
+func TestRenameBackupDirInsideDir(t *testing.T) {
+	tmpDir := copyTestData(t)
+	backupDir := filepath.Join(tmpDir, "zz-backup")
+
+	for i := 0; i < 2; i++ {
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.BackupDir = backupDir
+		err := o.Run()
+		require.NoError(t, err, "failed to run in dir %s", tmpDir)
+	}
+
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+	assert.FileExists(t, filepath.Join(backupDir, "resource100.yaml"), "should not rename the backed up files")
+	assert.NoFileExists(t, filepath.Join(backupDir, "cheese-svc.yaml"), "should not rename the backed up files")
+	assert.NoDirExists(t, filepath.Join(backupDir, "zz-backup"), "should not backup the backup dir")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.BackupDir = backupDir
+	o.Restore = true
+	err := o.Run()
+	require.NoError(t, err, "failed to restore in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+}
+
+func TestRenameSummaryYAML(t *testing.T) {
+	tmpDir := copyTestData(t)
+	summaryFile := filepath.Join(tmpDir, "summary.yaml")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.SummaryYAML = summaryFile
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	summary := &rename.Summary{}
+	err = yamls.LoadFile(summaryFile, summary)
+	require.NoError(t, err, "failed to load file %s", summaryFile)
+
+	assert.Equal(t, len(o.Results), summary.Scanned, "scanned")
+	assert.Equal(t, summary.Scanned, summary.Renamed+summary.Skipped+summary.Errors, "total")
+	assert.Equal(t, len(summary.Renames), summary.Renamed, "renames")
+	assert.Equal(t, 0, summary.Errors, "errors")
+
+	found := false
+	for _, r := range summary.Renames {
+		if filepath.Base(r.From) == "resource100.yaml" {
+			assert.Equal(t, filepath.Join(tmpDir, "cheese-svc.yaml"), r.To, "rename of %s", r.From)
+			assert.Equal(t, "Service", r.Kind, "kind of %s", r.From)
+			found = true
+		}
+	}
+	assert.True(t, found, "should have found the rename of resource100.yaml")
+}
+
+func TestRenameEmitInverseMap(t *testing.T) {
+	tmpDir := copyTraceTestData(t)
+	subDir := filepath.Join(tmpDir, "sub")
+	err := os.MkdirAll(subDir, files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create dir %s", subDir)
+	err = os.Rename(filepath.Join(tmpDir, "resource41.yaml"), filepath.Join(subDir, "resource41.yaml"))
+	require.NoError(t, err, "failed to move resource41.yaml")
+
+	outDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	mapFile := filepath.Join(outDir, "inverse.json")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.InverseMap = mapFile
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	data, err := ioutil.ReadFile(mapFile)
+	require.NoError(t, err, "failed to load file %s", mapFile)
+	inverseMap := map[string]string{}
+	err = json.Unmarshal(data, &inverseMap)
+	require.NoError(t, err, "failed to parse file %s", mapFile)
+
+	assert.Equal(t, "resource100.yaml", inverseMap["cheese-svc.yaml"], "original name of cheese-svc.yaml")
+	assert.Equal(t, filepath.Join("sub", "resource41.yaml"), inverseMap[filepath.Join("sub", "tekton-pipelines-controller-svc.yaml")], "original name in a sub dir")
+	assert.NotContains(t, inverseMap, "cheese-ksvc.yaml", "should not include files which were not renamed")
+
+	renamed := 0
+	for _, r := range o.Results {
+		if r.Action == rename.ActionRenamed {
+			renamed++
+		}
+	}
+	assert.Len(t, inverseMap, renamed, "should have an entry for each renamed file")
+}
+
+func TestRenameGitSubmodules(t *testing.T) {
+	for _, follow := range []bool{false, true} {
+		tmpDir := copyTestData(t)
+		submoduleDir := filepath.Join(tmpDir, "mysubmodule")
+		err := os.MkdirAll(submoduleDir, files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir %s", submoduleDir)
+		err = ioutil.WriteFile(filepath.Join(submoduleDir, ".git"), []byte("gitdir: ../.git/modules/mysubmodule\n"), files.DefaultFileWritePermissions)
+		require.NoError(t, err, "failed to save the .git file")
+		err = os.Rename(filepath.Join(tmpDir, "resource100.yaml"), filepath.Join(submoduleDir, "resource100.yaml"))
+		require.NoError(t, err, "failed to move resource100.yaml")
+
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.FollowGitSubmodules = follow
+		err = o.Run()
+		require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+		assert.FileExists(t, filepath.Join(tmpDir, "tekton-pipelines-controller-svc.yaml"), "should rename files outside the submodule when following submodules is %v", follow)
+		if follow {
+			assert.FileExists(t, filepath.Join(submoduleDir, "cheese-svc.yaml"), "should rename files in the submodule")
+			assert.NoFileExists(t, filepath.Join(submoduleDir, "resource100.yaml"), "should rename files in the submodule")
+		} else {
+			assert.FileExists(t, filepath.Join(submoduleDir, "resource100.yaml"), "should not rename files in the submodule")
+			assert.NoFileExists(t, filepath.Join(submoduleDir, "cheese-svc.yaml"), "should not rename files in the submodule")
+		}
+	}
+}
+
+func TestRenameEmitNoop(t *testing.T) {
+	for _, emitNoop := range []bool{false, true} {
+		tmpDir := copyTraceTestData(t)
+
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.EmitNoop = emitNoop
+		var err error
+		output := log.CaptureOutput(func() {
+			err = o.Run()
+		})
+		require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+		if emitNoop {
+			assert.Contains(t, output, "already canonical: cheese-ksvc.yaml", "should report the file which already has its canonical name")
+		} else {
+			assert.NotContains(t, output, "already canonical", "should not report files which already have their canonical name")
+		}
+		assert.NotContains(t, output, "already canonical: resource100.yaml", "should not report renamed files with --emit-noop %v", emitNoop)
+	}
+}
+
+func TestRenameOutputDir(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	outDir := filepath.Join(tmpDir, "output")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = "test_data"
+	o.OutputDir = outDir
+	o.NoCreateDir = true
+	err = o.Run()
+	require.Error(t, err, "should have failed as the output dir does not exist")
+
+	_, o = rename.NewCmdRename()
+	o.Dir = "test_data"
+	o.OutputDir = outDir
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", outDir)
+
+	assert.FileExists(t, filepath.Join(outDir, "cheese-svc.yaml"))
+	assert.FileExists(t, filepath.Join("test_data", "resource100.yaml"))
+}
+
+func TestRenameCompareContent(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(tmpDir, name))
+		require.NoError(t, err, "failed to copy file %s", name)
+	}
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.CompareContent = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "a.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "b.yaml"), "should not have renamed the duplicate file")
+}
+
+func TestRenameCheckDuplicateContent(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	paths := []string{filepath.Join(tmpDir, "a.yaml"), filepath.Join(tmpDir, "b.yaml"), filepath.Join(tmpDir, "sub", "c.yaml")}
+	for _, path := range paths {
+		err = os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir for %s", path)
+		err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), path)
+		require.NoError(t, err, "failed to copy file %s", path)
+	}
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.CheckDuplicateContent = true
+	o.ReadOnly = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	duplicates := map[string]string{}
+	for _, r := range o.Results {
+		duplicates[r.Path] = r.Duplicate
+	}
+	assert.Equal(t, map[string]string{paths[0]: "", paths[1]: paths[0], paths[2]: ""}, duplicates, "duplicates should only be found in the same directory")
+}
+
+func TestRenameTargetKind(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.TargetKinds = []string{"service"}
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "resource40.yaml"), "should not have renamed the deployment")
+}
+
+func TestRenameIgnoreCRDs(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.IgnoreCRDs = true
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "resource24.yaml"), "should not rename CRDs")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "runs.tekton.dev-crd.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+}
+
+func TestRenameWarnNonStandardExtensions(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(tmpDir, "resource100.yml"))
+	require.NoError(t, err, "failed to copy file")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.WarnNonStandardExts = true
+	o.Strict = true
+	err = o.Run()
+	require.Error(t, err, "should fail for .yml files in strict mode")
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yml"))
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.WarnNonStandardExts = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yml"), "should keep the .yml extension")
+}
+
+func TestRenameEmitGraphviz(t *testing.T) {
+	tmpDir := copyTestData(t)
+	graphFile := filepath.Join(tmpDir, "kinds.dot")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.EmitGraphviz = graphFile
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	data, err := ioutil.ReadFile(graphFile)
+	require.NoError(t, err, "failed to load file %s", graphFile)
+	text := string(data)
+	assert.True(t, strings.HasPrefix(text, "digraph kinds {"), "graph %s", text)
+	assert.Contains(t, text, `ClusterRole [ label="ClusterRole\n6 files", shape=box ];`, "graph %s", text)
+	assert.Contains(t, text, fmt.Sprintf("%q->ClusterRole[ label=6 ];", tmpDir), "graph %s", text)
+}
+
+func TestRenameOutputFormat(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	for _, format := range rename.OutputFormats {
+		buf := &bytes.Buffer{}
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.OutputFormat = format
+		o.Out = buf
+		err := o.Run()
+		require.NoError(t, err, "failed to run with format %s", format)
+
+		text := buf.String()
+		source := filepath.Join(tmpDir, "resource100.yaml")
+		target := filepath.Join(tmpDir, "cheese-svc.yaml")
+		switch format {
+		case rename.OutputFormatNames:
+			assert.Contains(t, text, target+"\t"+source+"\n", "output for format %s", format)
+		case rename.OutputFormatPairs:
+			assert.Contains(t, text, source+" => "+target+"\n", "output for format %s", format)
+		case rename.OutputFormatJSON:
+			var names []rename.CanonicalName
+			err = json.Unmarshal(buf.Bytes(), &names)
+			require.NoError(t, err, "failed to parse JSON output %s", text)
+			assert.Contains(t, names, rename.CanonicalName{Path: source, Canonical: target, Kind: "Service", Name: "cheese"})
+		}
+	}
+
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"), "should not have renamed any files")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+}
+
+func TestRenameReportHTML(t *testing.T) {
+	tmpDir := copyTestData(t)
+	reportFile := filepath.Join(tmpDir, "report.html")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.Report = reportFile
+	o.ReportFormat = rename.ReportFormatHTML
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	data, err := ioutil.ReadFile(reportFile)
+	require.NoError(t, err, "failed to load file %s", reportFile)
+	assert.Contains(t, string(data), "<td>resource100.yaml</td><td>cheese-svc.yaml</td><td>Service</td><td>cheese</td><td>renamed</td>", "report %s", reportFile)
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.Report = reportFile
+	o.ReportFormat = "xml"
+	err = o.Run()
+	require.Error(t, err, "should have failed for an unsupported report format")
+}
+
+func TestRenameDepth(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	dirs := []string{tmpDir, filepath.Join(tmpDir, "a"), filepath.Join(tmpDir, "a", "b")}
+	for _, dir := range dirs {
+		err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir %s", dir)
+		err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(dir, "resource100.yaml"))
+		require.NoError(t, err, "failed to copy file to %s", dir)
+	}
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.Depth = 1
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "a", "cheese-svc.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "a", "b", "resource100.yaml"), "should not have renamed files below the depth")
+}
+
+func TestRenameRecursiveLimit(t *testing.T) {
+	testCases := []struct {
+		name      string
+		limit     int
+		depth     int
+		recursive bool
+		renamed   []string
+		fail      bool
+	}{
+		{name: "limit", limit: 1, depth: -1, recursive: true, renamed: []string{".", "a"}},
+		{name: "not-recursive", limit: -1, depth: -1, recursive: false, renamed: []string{"."}},
+		{name: "not-recursive-with-limit", limit: 1, depth: -1, recursive: false, fail: true},
+		{name: "conflicting-depth", limit: 1, depth: 2, recursive: true, fail: true},
+	}
+
+	for _, tc := range testCases {
+		tmpDir, err := ioutil.TempDir("", "")
+		require.NoError(t, err, "could not create temp dir")
+
+		dirs := []string{".", "a", filepath.Join("a", "b")}
+		for _, dir := range dirs {
+			dir = filepath.Join(tmpDir, dir)
+			err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+			require.NoError(t, err, "failed to create dir %s", dir)
+			err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(dir, "resource100.yaml"))
+			require.NoError(t, err, "failed to copy file to %s", dir)
+		}
+
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.RecursiveLimit = tc.limit
+		o.Depth = tc.depth
+		o.Recursive = tc.recursive
+		err = o.Run()
+		if tc.fail {
+			require.Error(t, err, "should have failed for %s", tc.name)
+			continue
+		}
+		require.NoError(t, err, "failed to run %s in dir %s", tc.name, tmpDir)
+
+		for _, dir := range dirs {
+			expected := "resource100.yaml"
+			if stringhelpers.StringArrayIndex(tc.renamed, dir) >= 0 {
+				expected = "cheese-svc.yaml"
+			}
+			assert.FileExists(t, filepath.Join(tmpDir, dir, expected), "for %s", tc.name)
+		}
+	}
+}
+
+func TestRenameCheckGitTracked(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	for _, name := range []string{"resource100.yaml", "resource40.yaml"} {
+		err = files.CopyFile(filepath.Join("test_data", name), filepath.Join(tmpDir, name))
+		require.NoError(t, err, "failed to copy file %s", name)
+	}
+
+	runner := cmdrunner.QuietCommandRunner
+	for _, args := range [][]string{{"init"}, {"add", "resource100.yaml"}} {
+		_, err = runner(&cmdrunner.Command{Dir: tmpDir, Name: "git", Args: args})
+		require.NoError(t, err, "failed to run git %s", strings.Join(args, " "))
+	}
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.CheckGitTracked = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "resource40.yaml"), "should not have renamed the untracked file")
+}
+
+func TestRenameStrictYAML(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(tmpDir, "resource100.yaml"))
+	require.NoError(t, err, "failed to copy file")
+	invalid := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: duplicate\n  name: again\n"
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "invalid.yaml"), []byte(invalid), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save invalid file")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.StrictYAML = true
+	err = o.Run()
+	require.Error(t, err, "should have failed for duplicate keys")
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.StrictYAML = true
+	o.IgnoreErrors = true
+	err = o.Run()
+	require.NoError(t, err, "should have ignored the invalid file")
+
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "invalid.yaml"), "should not have renamed the invalid file")
+	assert.Equal(t, 1, o.CreateSummary().Errors, "error count")
+}
+
+func TestRenameUpdateArgoCDApps(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	dir := filepath.Join(tmpDir, "repo")
+	appDir := filepath.Join(tmpDir, "apps")
+	for _, d := range []string{filepath.Join(dir, "config"), filepath.Join(dir, "overlay"), appDir} {
+		err = os.MkdirAll(d, files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir %s", d)
+	}
+	for _, d := range []string{"config", "overlay"} {
+		err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(dir, d, "resource100.yaml"))
+		require.NoError(t, err, "failed to copy file")
+	}
+	kustomization := filepath.Join(dir, "overlay", "kustomization.yaml")
+	err = ioutil.WriteFile(kustomization, []byte("resources:\n- resource100.yaml\n- other.yaml\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", kustomization)
+
+	apps := map[string]string{
+		"cheese.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: cheese
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo.git
+    path: config
+    directory:
+      include: '{resource100.yaml,other.yaml}'
+`,
+		"glob.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: glob
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo.git
+    path: config
+    directory:
+      include: '*.yaml'
+`,
+		"overlay.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: overlay
+spec:
+  source:
+    repoURL: https://github.com/myorg/myrepo.git
+    path: overlay
+`,
+	}
+	for name, text := range apps {
+		path := filepath.Join(appDir, name)
+		err = ioutil.WriteFile(path, []byte(text), files.DefaultFileWritePermissions)
+		require.NoError(t, err, "failed to save file %s", path)
+	}
+	appFile := filepath.Join(appDir, "cheese.yaml")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = dir
+	o.UpdateArgoCDApps = true
+	o.ArgoCDAppDir = appDir
+	o.EmitGraph = true
+	o.GraphFile = filepath.Join(tmpDir, "rename.dot")
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", dir)
+
+	assert.FileExists(t, filepath.Join(dir, "config", "cheese-svc.yaml"))
+	data, err := ioutil.ReadFile(appFile)
+	require.NoError(t, err, "failed to load file %s", appFile)
+	assert.Contains(t, string(data), "include: '{cheese-svc.yaml,other.yaml}'", "application %s", appFile)
+	assert.Contains(t, string(data), "path: config\n", "application %s", appFile)
+
+	path := filepath.Join(appDir, "glob.yaml")
+	data, err = ioutil.ReadFile(path)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Contains(t, string(data), "include: '*.yaml'", "application %s", path)
+
+	data, err = ioutil.ReadFile(kustomization)
+	require.NoError(t, err, "failed to load file %s", kustomization)
+	assert.Contains(t, string(data), "- cheese-svc.yaml\n", "kustomization %s", kustomization)
+	assert.Contains(t, string(data), "- other.yaml\n", "kustomization %s", kustomization)
+	assert.NotContains(t, string(data), "resource100.yaml", "kustomization %s", kustomization)
+
+	data, err = ioutil.ReadFile(o.GraphFile)
+	require.NoError(t, err, "failed to load file %s", o.GraphFile)
+	assert.Contains(t, string(data), `"config/resource100.yaml"->"config/cheese-svc.yaml"[ label=renamed ];`, "graph %s", o.GraphFile)
+	assert.Contains(t, string(data), `"Application: `+appFile+`"->"config/cheese-svc.yaml"[ label=references ];`, "graph %s", o.GraphFile)
+	assert.Contains(t, string(data), `"Application: `+filepath.Join(appDir, "overlay.yaml")+`"->"overlay/cheese-svc.yaml"[ label=references ];`, "graph %s", o.GraphFile)
+}
+
+func TestRenameUpdateHelmfile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	for _, d := range []string{"values", "helmfile.d"} {
+		err = os.MkdirAll(filepath.Join(tmpDir, d), files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir %s", d)
+	}
+	err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(tmpDir, "values", "resource100.yaml"))
+	require.NoError(t, err, "failed to copy file")
+
+	helmfiles := map[string]string{
+		"helmfile.yaml": `releases:
+- name: cheese
+  chart: myrepo/cheese
+  values:
+  - values/resource100.yaml
+  - values/other.yaml
+`,
+		filepath.Join("helmfile.d", "apps.yaml"): `environments:
+  default:
+    values:
+    - ../values/resource100.yaml
+releases:
+- name: cheese
+  chart: myrepo/cheese
+  values:
+  - ../values/resource100.yaml
+`,
+	}
+	for name, text := range helmfiles {
+		path := filepath.Join(tmpDir, name)
+		err = ioutil.WriteFile(path, []byte(text), files.DefaultFileWritePermissions)
+		require.NoError(t, err, "failed to save file %s", path)
+	}
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.UpdateHelmfile = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "values", "cheese-svc.yaml"))
+
+	path := filepath.Join(tmpDir, "helmfile.yaml")
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Contains(t, string(data), "- values/cheese-svc.yaml", "helmfile %s", path)
+	assert.Contains(t, string(data), "- values/other.yaml", "helmfile %s", path)
+	assert.NotContains(t, string(data), "resource100.yaml", "helmfile %s", path)
+
+	path = filepath.Join(tmpDir, "helmfile.d", "apps.yaml")
+	data, err = ioutil.ReadFile(path)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Equal(t, 2, strings.Count(string(data), "- ../values/cheese-svc.yaml"), "helmfile %s", path)
+	assert.NotContains(t, string(data), "resource100.yaml", "helmfile %s", path)
+}
+
+func TestRenameUpdateFluxGitRepositories(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	dir := filepath.Join(tmpDir, "repo")
+	fluxDir := filepath.Join(tmpDir, "flux")
+	for _, d := range []string{filepath.Join(dir, "config"), filepath.Join(dir, "other"), fluxDir} {
+		err = os.MkdirAll(d, files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir %s", d)
+	}
+	for _, d := range []string{"config", "other"} {
+		err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(dir, d, "resource100.yaml"))
+		require.NoError(t, err, "failed to copy file")
+	}
+	kustomizations := map[string]string{}
+	for _, d := range []string{"config", "other"} {
+		path := filepath.Join(dir, d, "kustomization.yaml")
+		kustomizations[d] = path
+		err = ioutil.WriteFile(path, []byte("apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- resource100.yaml\n"), files.DefaultFileWritePermissions)
+		require.NoError(t, err, "failed to save file %s", path)
+	}
+
+	resources := map[string]string{
+		"kustomization.yaml": `apiVersion: kustomize.toolkit.fluxcd.io/v1beta1
+kind: Kustomization
+metadata:
+  name: cheese
+spec:
+  path: ./config
+  sourceRef:
+    kind: GitRepository
+    name: cheese
+`,
+		"gitrepository.yaml": `apiVersion: source.toolkit.fluxcd.io/v1beta1
+kind: GitRepository
+metadata:
+  name: cheese
+spec:
+  url: https://github.com/myorg/myrepo.git
+`,
+	}
+	for name, text := range resources {
+		path := filepath.Join(fluxDir, name)
+		err = ioutil.WriteFile(path, []byte(text), files.DefaultFileWritePermissions)
+		require.NoError(t, err, "failed to save file %s", path)
+	}
+
+	_, o := rename.NewCmdRename()
+	o.Dir = dir
+	o.UpdateFluxGitRepos = true
+	o.FluxDir = fluxDir
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", dir)
+
+	assert.FileExists(t, filepath.Join(dir, "config", "cheese-svc.yaml"))
+	path := kustomizations["config"]
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Contains(t, string(data), "- cheese-svc.yaml\n", "kustomization %s", path)
+	assert.NotContains(t, string(data), "resource100.yaml", "kustomization %s", path)
+
+	path = kustomizations["other"]
+	data, err = ioutil.ReadFile(path)
+	require.NoError(t, err, "failed to load file %s", path)
+	assert.Contains(t, string(data), "- resource100.yaml\n", "should not update a kustomization which is not referenced by a Flux Kustomization %s", path)
+
+	for name, text := range resources {
+		path = filepath.Join(fluxDir, name)
+		data, err = ioutil.ReadFile(path)
+		require.NoError(t, err, "failed to load file %s", path)
+		assert.Equal(t, text, string(data), "should not modify the Flux resource %s", path)
+	}
+}
+
+func TestRenameSuffixSeparator(t *testing.T) {
+	for _, flag := range []string{"--separator", "--suffix-separator"} {
+		tmpDir := copyTestData(t)
+
+		cmd, o := rename.NewCmdRename()
+		err := cmd.Flags().Parse([]string{flag, "_"})
+		require.NoError(t, err, "failed to parse flag %s", flag)
+		o.Dir = tmpDir
+		err = o.Run()
+		require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+		assert.FileExists(t, filepath.Join(tmpDir, "cheese_svc.yaml"), "file renamed using %s", flag)
+		assert.NoFileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"), "file renamed using %s", flag)
+	}
+}
+
+func TestRenameUpdateTerraformRefs(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	dir := filepath.Join(tmpDir, "repo")
+	tfDir := filepath.Join(tmpDir, "terraform")
+	for _, d := range []string{filepath.Join(dir, "manifests"), tfDir} {
+		err = os.MkdirAll(d, files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir %s", d)
+	}
+	err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(dir, "manifests", "resource100.yaml"))
+	require.NoError(t, err, "failed to copy file")
+
+	tf := `resource "kubernetes_manifest" "cheese" {
+  manifest = yamldecode(file("${path.module}/../repo/manifests/resource100.yaml"))
+}
+
+resource "kubernetes_manifest" "relative" {
+  manifest = yamldecode(templatefile("../repo/manifests/resource100.yaml", {}))
+}
+
+resource "kubernetes_manifest" "other" {
+  manifest = yamldecode(file("${path.root}/manifests/resource100.yaml"))
+}
+`
+	tfFile := filepath.Join(tfDir, "main.tf")
+	err = ioutil.WriteFile(tfFile, []byte(tf), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", tfFile)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = dir
+	o.UpdateTerraformRefs = true
+	o.TerraformDir = tfDir
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", dir)
+
+	assert.FileExists(t, filepath.Join(dir, "manifests", "cheese-svc.yaml"))
+	data, err := ioutil.ReadFile(tfFile)
+	require.NoError(t, err, "failed to load file %s", tfFile)
+	assert.Contains(t, string(data), `file("${path.module}/../repo/manifests/cheese-svc.yaml")`, "terraform %s", tfFile)
+	assert.Contains(t, string(data), `templatefile("../repo/manifests/cheese-svc.yaml", {})`, "terraform %s", tfFile)
+	assert.Contains(t, string(data), `file("${path.root}/manifests/resource100.yaml")`, "terraform %s", tfFile)
+}
+
+func TestRenameUpdateSkaffold(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	err = os.MkdirAll(filepath.Join(tmpDir, "k8s"), files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create dir")
+	err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(tmpDir, "k8s", "resource100.yaml"))
+	require.NoError(t, err, "failed to copy file")
+
+	skaffold := `apiVersion: skaffold/v2beta29
+kind: Config
+manifests:
+  rawYaml:
+  - k8s/resource100.yaml
+  - k8s/other.yaml
+profiles:
+- name: dev
+  deploy:
+    kubectl:
+      manifests:
+      - ./k8s/resource100.yaml
+`
+	skaffoldFile := filepath.Join(tmpDir, "skaffold.yaml")
+	err = ioutil.WriteFile(skaffoldFile, []byte(skaffold), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", skaffoldFile)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.UpdateSkaffold = true
+	o.SkaffoldFile = skaffoldFile
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "k8s", "cheese-svc.yaml"))
+	data, err := ioutil.ReadFile(skaffoldFile)
+	require.NoError(t, err, "failed to load file %s", skaffoldFile)
+	assert.Contains(t, string(data), "- k8s/cheese-svc.yaml", "skaffold file %s", skaffoldFile)
+	assert.Contains(t, string(data), "- ./k8s/cheese-svc.yaml", "skaffold file %s", skaffoldFile)
+	assert.Contains(t, string(data), "- k8s/other.yaml", "skaffold file %s", skaffoldFile)
+	assert.NotContains(t, string(data), "resource100.yaml", "skaffold file %s", skaffoldFile)
+}
+
+func TestRenameUpdateCompose(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	err = os.MkdirAll(filepath.Join(tmpDir, "config"), files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create dir")
+	err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(tmpDir, "config", "resource100.yaml"))
+	require.NoError(t, err, "failed to copy file")
+
+	compose := `services:
+  app:
+    image: myapp
+    volumes:
+    - ./config/resource100.yaml:/etc/app/service.yaml:ro
+    - data:/var/lib/app
+    - type: bind
+      source: ./config/resource100.yaml
+      target: /etc/app/other.yaml
+configs:
+  service:
+    file: ./config/resource100.yaml
+volumes:
+  data: {}
+`
+	composeFile := filepath.Join(tmpDir, "docker-compose.yml")
+	err = ioutil.WriteFile(composeFile, []byte(compose), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", composeFile)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.UpdateCompose = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "config", "cheese-svc.yaml"))
+	data, err := ioutil.ReadFile(composeFile)
+	require.NoError(t, err, "failed to load file %s", composeFile)
+	text := string(data)
+	assert.Contains(t, text, "- ./config/cheese-svc.yaml:/etc/app/service.yaml:ro", "compose file %s", composeFile)
+	assert.Contains(t, text, "source: ./config/cheese-svc.yaml", "compose file %s", composeFile)
+	assert.Contains(t, text, "file: ./config/cheese-svc.yaml", "compose file %s", composeFile)
+	assert.Contains(t, text, "- data:/var/lib/app", "compose file %s", composeFile)
+	assert.NotContains(t, text, "resource100.yaml", "compose file %s", composeFile)
+}
+
+func TestRenameTrimSuffix(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	for _, name := range []string{"cheese-svc.yaml", "other-cm.yaml"} {
+		err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(tmpDir, name))
+		require.NoError(t, err, "failed to copy file %s", name)
+	}
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.TrimSuffix = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "other-cm.yaml"), "should not trim a suffix of a different kind")
+}
+
+func TestRenameRegexReplace(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), filepath.Join(tmpDir, "resource100.yaml"))
+	require.NoError(t, err, "failed to copy file")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.RegexReplace = []string{"^(?P<first>ch)eese$=${first}ips", "ips$=ipz"}
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "chipz-svc.yaml"))
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.RegexReplace = []string{"([a-z=x"}
+	err = o.Run()
+	require.Error(t, err, "should have failed for an invalid regular expression")
+}
+
+func TestRenameOutputKV(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	buf := &bytes.Buffer{}
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.OutputKV = true
+	o.Out = buf
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.Contains(t, buf.String(), "\n_tmp_", "output")
+	assert.Contains(t, buf.String(), "_resource100_yaml='"+filepath.Join(tmpDir, "cheese-svc.yaml")+"'\n", "output")
+}
+
+func TestRenameOutputKVQuoting(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "it's a dir")
+	require.NoError(t, err, "could not create temp dir")
+	err = files.CopyDirOverwrite("test_data", tmpDir)
+	require.NoError(t, err, "failed to copy test data to %s", tmpDir)
+
+	for _, readOnly := range []bool{true, false} {
+		buf := &bytes.Buffer{}
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.OutputKV = true
+		o.ReadOnly = readOnly
+		o.Out = buf
+		err = o.Run()
+		require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.NotEmpty(t, lines, "output with read only %v", readOnly)
+		for _, line := range lines {
+			values := strings.SplitN(line, "=", 2)
+			require.Len(t, values, 2, "output line %s", line)
+			assert.Regexp(t, "^[A-Za-z_][A-Za-z0-9_]*$", values[0], "variable name with read only %v", readOnly)
+		}
+		name := regexp.MustCompile("[^A-Za-z0-9_]").ReplaceAllString(filepath.Join(tmpDir, "resource100.yaml"), "_")
+		value := "'" + strings.ReplaceAll(filepath.Join(tmpDir, "cheese-svc.yaml"), "'", `'\''`) + "'"
+		assert.Contains(t, lines, name+"="+value, "output with read only %v", readOnly)
+	}
+}
+
+func TestRenameLabelFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	outDir := filepath.Join(tmpDir, "output")
+
+	labelFile := filepath.Join(tmpDir, "labels.yaml")
+	err = ioutil.WriteFile(labelFile, []byte("resource10*.yaml:\n  team: cheese\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", labelFile)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = "test_data"
+	o.LabelFile = labelFile
+	err = o.Run()
+	require.Error(t, err, "should have failed without an output dir")
+
+	_, o = rename.NewCmdRename()
+	o.Dir = "test_data"
+	o.OutputDir = outDir
+	o.LabelFile = labelFile
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", outDir)
+
+	for name, expected := range map[string]string{
+		"cheese-svc.yaml":                         "cheese",
+		"tekton-pipelines-controller-deploy.yaml": "",
+	} {
+		path := filepath.Join(outDir, name)
+		resource := &unstructured.Unstructured{}
+		err = yamls.LoadFile(path, resource)
+		require.NoError(t, err, "failed to load file %s", path)
+		assert.Equal(t, expected, resource.GetLabels()["team"], "team label of %s", path)
+	}
+}
+
+func TestRenameNoFollowSymlinks(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	dir := filepath.Join(tmpDir, "repo")
+	err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create dir %s", dir)
+
+	target := filepath.Join(tmpDir, "resource100.yaml")
+	err = files.CopyFile(filepath.Join("test_data", "resource100.yaml"), target)
+	require.NoError(t, err, "failed to copy file")
+	link := filepath.Join(dir, "link.yaml")
+	err = os.Symlink(target, link)
+	require.NoError(t, err, "failed to create symlink %s", link)
+	err = os.Symlink(dir, filepath.Join(dir, "loop"))
+	require.NoError(t, err, "failed to create circular symlink")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = dir
+	o.NoFollowSymlinks = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", dir)
+
+	assert.FileExists(t, link, "should not have renamed the symlink")
+	assert.NoFileExists(t, filepath.Join(dir, "cheese-svc.yaml"))
+}
+
+func TestRenameEmitRenamedOnly(t *testing.T) {
+	tmpDir := copyTestData(t)
+	expected := filepath.Join(tmpDir, "resource100.yaml") + " => " + filepath.Join(tmpDir, "cheese-svc.yaml") + "\n"
+
+	for _, readOnly := range []bool{true, false} {
+		buf := &bytes.Buffer{}
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.ReadOnly = readOnly
+		o.EmitRenamedOnly = true
+		o.Out = buf
+		err := o.Run()
+		require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+		assert.Contains(t, buf.String(), expected, "output with read only %v", readOnly)
+	}
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+}
+
+func TestRenameSummaryOnly(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	buf := &bytes.Buffer{}
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.SummaryOnly = true
+	o.Out = buf
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	summary := o.CreateSummary()
+	assert.NotZero(t, summary.Renamed, "should have renamed files")
+	assert.Equal(t, fmt.Sprintf("%d renamed, %d skipped, %d errors\n", summary.Renamed, summary.Skipped, summary.Errors), buf.String())
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.SummaryOnly = true
+	o.EmitRenamedOnly = true
+	err = o.Run()
+	require.Error(t, err, "should fail when combined with --emit-renamed-only")
+}
+
+func TestRenameEmitMetrics(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	buf := &bytes.Buffer{}
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.EmitMetrics = true
+	o.Out = buf
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	summary := o.CreateSummary()
+	text := buf.String()
+	assert.Contains(t, text, "# TYPE rename_files_total gauge\n", "metrics")
+	assert.Contains(t, text, fmt.Sprintf("rename_files_total %d\n", summary.Scanned), "metrics")
+	assert.Contains(t, text, fmt.Sprintf("rename_files_renamed %d\n", summary.Renamed), "metrics")
+	assert.Contains(t, text, fmt.Sprintf("rename_files_skipped %d\n", summary.Skipped), "metrics")
+	assert.Contains(t, text, "rename_files_errored 0\n", "metrics")
+	assert.Contains(t, text, "# HELP rename_duration_seconds ", "metrics")
+}
+
+func TestRenameEmitCSV(t *testing.T) {
+	tmpDir := copyTestData(t)
+	csvDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	csvFile := filepath.Join(csvDir, "renames.csv")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.ReadOnly = true
+	o.EmitCSV = csvFile
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"), "should not have renamed the file in read only mode")
+	data, err := ioutil.ReadFile(csvFile)
+	require.NoError(t, err, "failed to load file %s", csvFile)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, `"original","canonical","kind","name","action","error"`, lines[0], "CSV header")
+	assert.Len(t, lines, len(o.Results)+1, "CSV rows")
+	assert.Contains(t, lines, `"resource100.yaml","cheese-svc.yaml","Service","cheese","skipped",""`, "CSV file %s", csvFile)
+}
+
+func TestRenameOutputJSONL(t *testing.T) {
+	tmpDir := copyTestData(t)
+	outDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	jsonlFile := filepath.Join(outDir, "renames.jsonl")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.OutputJSONL = jsonlFile
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	data, err := ioutil.ReadFile(jsonlFile)
+	require.NoError(t, err, "failed to load file %s", jsonlFile)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, len(o.Results), "should have an entry for each file visited")
+
+	entries := map[string]map[string]interface{}{}
+	for _, line := range lines {
+		entry := map[string]interface{}{}
+		err = json.Unmarshal([]byte(line), &entry)
+		require.NoError(t, err, "failed to parse JSON Lines entry %s", line)
+		assert.Contains(t, entry, "error", "entry %s", line)
+		entries[entry["path"].(string)] = entry
+	}
+	entry := entries["resource100.yaml"]
+	require.NotNil(t, entry, "should have an entry for resource100.yaml")
+	assert.Equal(t, "cheese-svc.yaml", entry["canonical"], "canonical")
+	assert.Equal(t, "Service", entry["kind"], "kind")
+	assert.Equal(t, "cheese", entry["name"], "name")
+	assert.Equal(t, "renamed", entry["action"], "action")
+	assert.Nil(t, entry["error"], "error")
+}
+
+func TestRenameTraceFile(t *testing.T) {
+	tmpDir := copyTraceTestData(t)
+	outDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+	traceFile := filepath.Join(outDir, "trace.jsonl")
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.TraceFile = traceFile
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	data, err := ioutil.ReadFile(traceFile)
+	require.NoError(t, err, "failed to load file %s", traceFile)
+	assertTraceEntries(t, tmpDir, string(data), len(o.Results))
+}
+
+func TestRenameTrace(t *testing.T) {
+	tmpDir := copyTraceTestData(t)
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err, "failed to create pipe")
+	output := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		output <- string(data)
+	}()
+
+	stderr := os.Stderr
+	os.Stderr = w
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.Trace = true
+	err = o.Run()
+	os.Stderr = stderr
+	w.Close()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assertTraceEntries(t, tmpDir, <-output, len(o.Results))
+}
+
+// copyTraceTestData copies the test data with one file already using its canonical name so that it is skipped
+func copyTraceTestData(t *testing.T) string {
+	tmpDir := copyTestData(t)
+	err := os.Rename(filepath.Join(tmpDir, "resource101.yaml"), filepath.Join(tmpDir, "cheese-ksvc.yaml"))
+	require.NoError(t, err, "failed to rename resource101.yaml")
+	return tmpDir
+}
+
+// assertTraceEntries asserts the trace has an entry for each file including a rename and a skip
+func assertTraceEntries(t *testing.T, dir, text string, count int) {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	require.Len(t, lines, count, "should have a trace entry for each file visited")
+
+	entries := map[string]*rename.TraceEntry{}
+	for _, line := range lines {
+		entry := &rename.TraceEntry{}
+		err := json.Unmarshal([]byte(line), entry)
+		require.NoError(t, err, "failed to parse trace entry %s", line)
+		entries[entry.Path] = entry
+	}
+
+	entry := entries[filepath.Join(dir, "resource100.yaml")]
+	require.NotNil(t, entry, "should have a trace entry for resource100.yaml")
+	assert.Equal(t, filepath.Join(dir, "cheese-svc.yaml"), entry.Canonical, "canonical")
+	assert.Equal(t, "Service", entry.Kind, "kind")
+	assert.Equal(t, "cheese", entry.Name, "name")
+	assert.Equal(t, rename.ActionRenamed, entry.Action, "action")
+	assert.Empty(t, entry.Error, "error")
+
+	entry = entries[filepath.Join(dir, "cheese-ksvc.yaml")]
+	require.NotNil(t, entry, "should have a trace entry for cheese-ksvc.yaml")
+	assert.Equal(t, filepath.Join(dir, "cheese-ksvc.yaml"), entry.Canonical, "canonical")
+	assert.Equal(t, rename.ActionSkipped, entry.Action, "action")
+	assert.Empty(t, entry.Error, "error")
+}
+
+func TestRenameFormat(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	buf := &bytes.Buffer{}
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.OutputRelativePaths = true
+	o.Format = `{{ if eq .Action "renamed" }}mv {{ .From }} {{ .To }} # {{ .Kind }}/{{ .Name }}{{ end }}`
+	o.Out = buf
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, o.CreateSummary().Renamed, "only the renamed files should be output")
+	assert.Contains(t, lines, "mv resource100.yaml cheese-svc.yaml # Service/cheese", "formatted output")
+
+	buf = &bytes.Buffer{}
+	_, o = rename.NewCmdRename()
+	o.Dir = copyTestData(t)
+	o.ReadOnly = true
+	o.OutputRelativePaths = true
+	o.Format = rename.DefaultFormat
+	o.Out = buf
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", o.Dir)
+	assert.Contains(t, buf.String(), "resource100.yaml => cheese-svc.yaml\n", "default format output")
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.Format = "{{ .From "
+	err = o.Run()
+	require.Error(t, err, "should fail for an invalid template")
+}
+
+func TestRenameEmitTable(t *testing.T) {
+	columns := os.Getenv("COLUMNS")
+	defer os.Setenv("COLUMNS", columns)
+
+	for _, width := range []string{"", "140"} {
+		os.Setenv("COLUMNS", width)
+		tmpDir := copyTestData(t)
+
+		buf := &bytes.Buffer{}
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.EmitTable = true
+		o.Out = buf
+		err := o.Run()
+		require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.NotEmpty(t, lines, "no table output for width %s", width)
+		assert.Equal(t, []string{"ORIGINAL", "CANONICAL", "KIND", "NAME", "ACTION"}, strings.Fields(lines[0]), "table header for width %s", width)
+
+		found := false
+		previous := ""
+		for _, line := range lines[1:] {
+			fields := strings.Fields(line)
+			if fields[0] == "resource100.yaml" {
+				assert.Equal(t, []string{"resource100.yaml", "cheese-svc.yaml", "Service", "cheese", "renamed"}, fields, "row for width %s", width)
+				found = true
+			}
+			if width != "" {
+				assert.True(t, len(strings.TrimRight(line, " ")) <= 140, "line %s should be truncated to width %s", line, width)
+			}
+			action := fields[len(fields)-1]
+			assert.True(t, previous <= action, "rows should be sorted by action but %s came after %s", action, previous)
+			previous = action
+		}
+		assert.True(t, found, "no row for resource100.yaml for width %s in %s", width, buf.String())
+	}
+}
+
+func TestRenameSimulateError(t *testing.T) {
+	tmpDir := copyTestData(t)
+	simulateError := func(path string) error {
+		return errors.Errorf("simulated error renaming %s", path)
+	}
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.SimulateError = simulateError
+	err := o.Run()
+	require.Error(t, err, "should have failed with a simulated error in dir %s", tmpDir)
+	assert.Contains(t, err.Error(), "simulated error", "error message")
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.SimulateError = simulateError
+	o.IgnoreErrors = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run with --ignore-errors in dir %s", tmpDir)
+	require.NotEmpty(t, o.Results, "no results")
+	for _, r := range o.Results {
+		if r.Canonical != "" {
+			assert.Equal(t, rename.ActionError, r.Action, "action of file %s", r.Path)
+		}
+	}
+	assert.FileExists(t, filepath.Join(tmpDir, "resource100.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+}
+
+func TestRenameParseComments(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	path := filepath.Join(tmpDir, "manifest.yaml")
+	err = ioutil.WriteFile(path, []byte("# kind: Deployment, name: frontend\nreplicas: 3\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", path)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+	assert.FileExists(t, path, "should not rename files without metadata by default")
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.ParseComments = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, filepath.Join(tmpDir, "frontend-deploy.yaml"))
+}
+
+func TestRenameSkipManaged(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	managed := filepath.Join(tmpDir, "managed.yaml")
+	err = ioutil.WriteFile(managed, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: managed\n  annotations:\n    jx.io/managed-by: helm\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", managed)
+	unmanaged := filepath.Join(tmpDir, "unmanaged.yaml")
+	err = ioutil.WriteFile(unmanaged, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: unmanaged\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", unmanaged)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.SkipManaged = "jx.io/managed-by"
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.FileExists(t, managed, "should not rename managed files")
+	assert.NoFileExists(t, unmanaged)
+	assert.FileExists(t, filepath.Join(tmpDir, "unmanaged-cm.yaml"))
+}
+
+func TestRenameIgnoreTemplateFiles(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	path := filepath.Join(tmpDir, "template.yaml")
+	err := ioutil.WriteFile(path, []byte("{{- if .Values.enabled }}\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name }}\n{{- end }}\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save file %s", path)
+
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	err = o.Run()
+	require.Error(t, err, "should fail to parse the template in dir %s", tmpDir)
+
+	_, o = rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.IgnoreTemplateFiles = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+	assert.FileExists(t, path, "should not rename template files")
+	assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"))
+}
+
+func TestRenameOutputRelativePaths(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	buf := &bytes.Buffer{}
+	_, o := rename.NewCmdRename()
+	o.Dir = tmpDir
+	o.ReadOnly = true
+	o.EmitRenamedOnly = true
+	o.OutputRelativePaths = true
+	o.Out = buf
+	err := o.Run()
+	require.NoError(t, err, "failed to run in dir %s", tmpDir)
+
+	assert.Contains(t, buf.String(), "\nresource100.yaml => cheese-svc.yaml\n", "output")
+	assert.NotContains(t, buf.String(), tmpDir, "output")
+}
+
+func TestRenameSymlinkLoop(t *testing.T) {
+	tmpDir := copyTestData(t)
+
+	// a link back to the root dir, links between two dirs and a circular link between two YAML files
+	err := os.Symlink(tmpDir, filepath.Join(tmpDir, "loop"))
+	require.NoError(t, err, "failed to create circular symlink")
+	a := filepath.Join(tmpDir, "a")
+	b := filepath.Join(tmpDir, "b")
+	for _, dir := range []string{a, b} {
+		err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+		require.NoError(t, err, "failed to create dir %s", dir)
+	}
+	err = os.Symlink(b, filepath.Join(a, "b"))
+	require.NoError(t, err, "failed to create symlink")
+	err = os.Symlink(a, filepath.Join(b, "a"))
+	require.NoError(t, err, "failed to create symlink")
+	err = os.Symlink(filepath.Join(tmpDir, "second.yaml"), filepath.Join(tmpDir, "first.yaml"))
+	require.NoError(t, err, "failed to create symlink")
+	err = os.Symlink(filepath.Join(tmpDir, "first.yaml"), filepath.Join(tmpDir, "second.yaml"))
+	require.NoError(t, err, "failed to create symlink")
+
+	for _, noFollow := range []bool{false, true} {
+		_, o := rename.NewCmdRename()
+		o.Dir = tmpDir
+		o.NoFollowSymlinks = noFollow
+
+		done := make(chan error, 1)
+		go func() {
+			done <- o.Run()
+		}()
+		select {
+		case err = <-done:
+		case <-time.After(30 * time.Second):
+			require.FailNow(t, "timed out", "the rename did not complete in dir %s with no follow symlinks %v", tmpDir, noFollow)
+		}
+		require.NoError(t, err, "failed to run in dir %s with no follow symlinks %v", tmpDir, noFollow)
+
+		assert.FileExists(t, filepath.Join(tmpDir, "cheese-svc.yaml"), "with no follow symlinks %v", noFollow)
+		for _, name := range []string{"first.yaml", "second.yaml"} {
+			_, err = os.Lstat(filepath.Join(tmpDir, name))
+			assert.NoError(t, err, "should not have renamed the symlink %s", name)
+		}
+		for _, r := range o.Results {
+			assert.False(t, strings.HasPrefix(r.Path, a+string(os.PathSeparator)), "walked into the linked dir %s", r.Path)
+			assert.False(t, strings.HasPrefix(r.Path, filepath.Join(tmpDir, "loop")+string(os.PathSeparator)), "walked into the linked dir %s", r.Path)
+		}
+	}
+}
+
+func copyTestData(t *testing.T) string {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "could not create temp dir")
+
+	err = files.CopyDirOverwrite("test_data", tmpDir)
+	require.NoError(t, err, "failed to copy test_data to %s", tmpDir)
+	return tmpDir
+}
@@ -0,0 +1,117 @@
+package rename
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io/ioutil"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ReportFormatJSON writes the report as JSON
+	ReportFormatJSON = "json"
+
+	// ReportFormatHTML writes the report as a HTML page
+	ReportFormatHTML = "html"
+)
+
+// ReportFormats the supported values of --report-format
+var ReportFormats = []string{ReportFormatJSON, ReportFormatHTML}
+
+// ReportEntry an entry in the rename report for a file
+type ReportEntry struct {
+	Path      string `json:"path"`
+	Canonical string `json:"canonical,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Action    string `json:"action"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CreateReport creates the report entries of the files processed
+func (o *Options) CreateReport() []ReportEntry {
+	answer := []ReportEntry{}
+	for _, r := range o.Results {
+		entry := ReportEntry{
+			Path:   o.relativePath(r.Path),
+			Kind:   r.Kind,
+			Name:   r.Name,
+			Action: r.Action,
+		}
+		if r.Canonical != "" {
+			entry.Canonical = o.relativePath(r.Canonical)
+		}
+		if r.Error != nil {
+			entry.Error = r.Error.Error()
+		}
+		answer = append(answer, entry)
+	}
+	return answer
+}
+
+func (o *Options) writeReport() error {
+	entries := o.CreateReport()
+
+	var data []byte
+	var err error
+	if o.ReportFormat == ReportFormatHTML {
+		buf := &bytes.Buffer{}
+		err = reportTemplate.Execute(buf, entries)
+		data = buf.Bytes()
+	} else {
+		data, err = json.MarshalIndent(entries, "", "  ")
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s report", o.ReportFormat)
+	}
+	err = ioutil.WriteFile(o.Report, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save report file %s", o.Report)
+	}
+	return nil
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Rename Report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #eee; cursor: pointer; }
+tr.error { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>Rename Report</h1>
+<table id="report">
+<thead>
+<tr><th>Original</th><th>Canonical</th><th>Kind</th><th>Name</th><th>Action</th><th>Error</th></tr>
+</thead>
+<tbody>
+{{- range . }}
+<tr class="{{ .Action }}"><td>{{ .Path }}</td><td>{{ .Canonical }}</td><td>{{ .Kind }}</td><td>{{ .Name }}</td><td>{{ .Action }}</td><td>{{ .Error }}</td></tr>
+{{- end }}
+</tbody>
+</table>
+<script>
+document.querySelectorAll("#report th").forEach(function(th, column) {
+  th.addEventListener("click", function() {
+    var tbody = document.querySelector("#report tbody");
+    var ascending = th.dataset.order !== "asc";
+    th.dataset.order = ascending ? "asc" : "desc";
+    Array.from(tbody.rows).sort(function(a, b) {
+      var x = a.cells[column].textContent, y = b.cells[column].textContent;
+      return ascending ? x.localeCompare(y) : y.localeCompare(x);
+    }).forEach(function(row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
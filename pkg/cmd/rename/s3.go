@@ -0,0 +1,210 @@
+package rename
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const s3Scheme = "s3://"
+
+// S3API the S3 API used to download and upload the files
+type S3API interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// s3Location a bucket and key prefix
+type s3Location struct {
+	Bucket string
+	Prefix string
+}
+
+// parseS3URL parses the s3://bucket/prefix URL
+func parseS3URL(text string) (*s3Location, error) {
+	if !strings.HasPrefix(text, s3Scheme) {
+		return nil, errors.Errorf("the S3 URL %s does not start with %s", text, s3Scheme)
+	}
+	u, err := url.Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse S3 URL %s", text)
+	}
+	if u.Host == "" {
+		return nil, errors.Errorf("no bucket in S3 URL %s", text)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Location{Bucket: u.Host, Prefix: prefix}, nil
+}
+
+// key returns the key of the given path relative to the location
+func (l *s3Location) key(rel string) string {
+	return l.Prefix + filepath.ToSlash(rel)
+}
+
+// s3Client lazily creates the S3 client using the standard credential chain
+func (o *Options) s3Client() error {
+	if o.S3Client != nil {
+		return nil
+	}
+	var opts []func(*config.LoadOptions) error
+	if o.AWSRegion != "" {
+		opts = append(opts, config.WithRegion(o.AWSRegion))
+	}
+	if o.AWSProfile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(o.AWSProfile))
+	}
+	cfg, err := config.LoadDefaultConfig(context.TODO(), opts...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load AWS configuration")
+	}
+	o.S3Client = s3.NewFromConfig(cfg)
+	return nil
+}
+
+// downloadS3Source downloads the YAML files in the S3 source into a temporary directory which is then renamed
+func (o *Options) downloadS3Source() error {
+	source, err := parseS3URL(o.S3Source)
+	if err != nil {
+		return err
+	}
+	o.s3Source = source
+	o.s3Dest = source
+	if o.S3Dest != "" {
+		o.s3Dest, err = parseS3URL(o.S3Dest)
+		if err != nil {
+			return err
+		}
+	}
+	err = o.s3Client()
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "jx-rename-s3-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temp dir")
+	}
+	o.s3TempDir = dir
+	o.Dir = dir
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(o.S3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(source.Bucket),
+		Prefix: aws.String(source.Prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return errors.Wrapf(err, "failed to list objects in %s", o.S3Source)
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if isYAMLFile(key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		rel := strings.TrimPrefix(key, source.Prefix)
+		localPath := filepath.Join(dir, filepath.FromSlash(rel))
+		err = o.downloadS3Object(source.Bucket, key, localPath)
+		if err != nil {
+			return err
+		}
+	}
+	log.Logger().Infof("downloaded %d YAML files from %s", len(keys), o.S3Source)
+	return nil
+}
+
+func (o *Options) downloadS3Object(bucket, key, localPath string) error {
+	output, err := o.S3Client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get object %s from bucket %s", key, bucket)
+	}
+	defer output.Body.Close()
+
+	data, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read object %s from bucket %s", key, bucket)
+	}
+	err = os.MkdirAll(filepath.Dir(localPath), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir for %s", localPath)
+	}
+	err = ioutil.WriteFile(localPath, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", localPath)
+	}
+	return nil
+}
+
+// uploadS3Results uploads the renamed files to the S3 destination.
+//
+// If the destination is the source then only the renamed files are uploaded and their original objects are deleted.
+// Otherwise all of the files are uploaded using their canonical names.
+func (o *Options) uploadS3Results() error {
+	inPlace := *o.s3Source == *o.s3Dest
+	count := 0
+	for _, r := range o.Results {
+		if r.Action == ActionError {
+			continue
+		}
+		renamed := r.Action == ActionRenamed
+		if inPlace && !renamed {
+			continue
+		}
+		localPath := r.Path
+		if renamed {
+			localPath = r.Canonical
+		}
+		data, err := ioutil.ReadFile(localPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load file %s", localPath)
+		}
+		key := o.s3Dest.key(o.relativePath(localPath))
+		_, err = o.S3Client.PutObject(context.TODO(), &s3.PutObjectInput{
+			Bucket: aws.String(o.s3Dest.Bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(data),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to put object %s in bucket %s", key, o.s3Dest.Bucket)
+		}
+		count++
+
+		if inPlace {
+			oldKey := o.s3Source.key(o.relativePath(r.Path))
+			_, err = o.S3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+				Bucket: aws.String(o.s3Source.Bucket),
+				Key:    aws.String(oldKey),
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete object %s in bucket %s", oldKey, o.s3Source.Bucket)
+			}
+			log.Logger().Infof("renamed %s%s => %s%s", s3Scheme, path.Join(o.s3Source.Bucket, oldKey), s3Scheme, path.Join(o.s3Dest.Bucket, key))
+		}
+	}
+	log.Logger().Infof("uploaded %d YAML files to %s%s/%s", count, s3Scheme, o.s3Dest.Bucket, o.s3Dest.Prefix)
+	return nil
+}
@@ -0,0 +1,102 @@
+package rename_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jenkins-x/jx-gitops/pkg/cmd/rename"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 an in memory S3 API storing objects by bucket/key
+type fakeS3 struct {
+	objects map[string]string
+}
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	prefix := aws.ToString(input.Bucket) + "/" + aws.ToString(input.Prefix)
+	page := &s3.ListObjectsV2Output{}
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			key := strings.TrimPrefix(k, aws.ToString(input.Bucket)+"/")
+			page.Contents = append(page.Contents, types.Object{Key: aws.String(key)})
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	text := f.objects[aws.ToString(input.Bucket)+"/"+aws.ToString(input.Key)]
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(strings.NewReader(text)),
+	}, nil
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	buf := &bytes.Buffer{}
+	_, err := buf.ReadFrom(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[aws.ToString(input.Bucket)+"/"+aws.ToString(input.Key)] = buf.String()
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	delete(f.objects, aws.ToString(input.Bucket)+"/"+aws.ToString(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) keys() []string {
+	var answer []string
+	for k := range f.objects {
+		answer = append(answer, k)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+func TestRenameS3Source(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("test_data", "resource100.yaml"))
+	require.NoError(t, err, "failed to load test data")
+
+	newFake := func() *fakeS3 {
+		return &fakeS3{
+			objects: map[string]string{
+				"mybucket/config/resource100.yaml": string(data),
+				"mybucket/config/README.md":        "not YAML",
+				"mybucket/other/resource100.yaml":  string(data),
+			},
+		}
+	}
+
+	fake := newFake()
+	_, o := rename.NewCmdRename()
+	o.S3Source = "s3://mybucket/config"
+	o.S3Client = fake
+	err = o.Run()
+	require.NoError(t, err, "failed to rename in S3")
+
+	assert.Equal(t, []string{"mybucket/config/README.md", "mybucket/config/cheese-svc.yaml", "mybucket/other/resource100.yaml"}, fake.keys(), "objects renamed in place")
+	assert.Equal(t, string(data), fake.objects["mybucket/config/cheese-svc.yaml"], "renamed object content")
+	assert.NoDirExists(t, o.Dir, "should have removed the temporary dir")
+
+	fake = newFake()
+	_, o = rename.NewCmdRename()
+	o.S3Source = "s3://mybucket/config"
+	o.S3Dest = "s3://otherbucket/canonical/"
+	o.S3Client = fake
+	err = o.Run()
+	require.NoError(t, err, "failed to rename in S3")
+
+	assert.Equal(t, []string{"mybucket/config/README.md", "mybucket/config/resource100.yaml", "mybucket/other/resource100.yaml", "otherbucket/canonical/cheese-svc.yaml"}, fake.keys(), "objects copied to the destination")
+}
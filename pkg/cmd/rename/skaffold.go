@@ -0,0 +1,125 @@
+package rename
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// skaffoldManifestPaths the paths of the lists of manifest files in a skaffold configuration or profile
+var skaffoldManifestPaths = [][]string{
+	{"manifests", "rawYaml"},
+	{"deploy", "kubectl", "manifests"},
+}
+
+// updateSkaffold updates the manifest references in the skaffold configuration which reference a renamed file
+func (o *Options) updateSkaffold() error {
+	renames := map[string]string{}
+	for canonical, original := range o.CreateInverseMap() {
+		renames[filepath.ToSlash(original)] = filepath.ToSlash(canonical)
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	path := o.SkaffoldFile
+	if path == "" {
+		path = filepath.Join(o.Dir, "skaffold.yaml")
+	}
+	// the skaffold configuration may have been renamed itself
+	if canonical := renames[filepath.ToSlash(o.relativePath(path))]; canonical != "" {
+		path = filepath.Join(o.Dir, canonical)
+	}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		if o.SkaffoldFile != "" {
+			return errors.Errorf("the skaffold file %s does not exist", path)
+		}
+		return nil
+	}
+	err = o.updateSkaffoldFile(path, renames)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update skaffold file %s", path)
+	}
+	return nil
+}
+
+func (o *Options) updateSkaffoldFile(path string, renames map[string]string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+	nodes, err := (&kio.ByteReader{Reader: bytes.NewReader(data), OmitReaderAnnotations: true}).Read()
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse skaffold file %s", path)
+	}
+
+	dir := filepath.Dir(path)
+	modified := false
+	updateManifests := func(node *yaml.RNode) error {
+		for _, fields := range skaffoldManifestPaths {
+			manifests, err := node.Pipe(yaml.Lookup(fields...))
+			if err != nil {
+				return errors.Wrapf(err, "failed to find %s", strings.Join(fields, "."))
+			}
+			if manifests == nil || manifests.YNode().Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, value := range manifests.YNode().Content {
+				if value.Kind != yaml.ScalarNode {
+					continue
+				}
+				canonical, newValue := o.renamedFileReference(dir, value.Value, renames)
+				if newValue == "" {
+					continue
+				}
+				log.Logger().Infof("updated skaffold file %s manifest %s => %s", o.relativePath(path), value.Value, newValue)
+				o.references = append(o.references, reference{Kind: "Skaffold", Path: o.relativePath(path), File: canonical})
+				value.Value = newValue
+				modified = true
+			}
+		}
+		return nil
+	}
+
+	for _, node := range nodes {
+		err = updateManifests(node)
+		if err != nil {
+			return err
+		}
+		profiles, err := node.Pipe(yaml.Lookup("profiles"))
+		if err != nil {
+			return errors.Wrapf(err, "failed to find profiles")
+		}
+		if profiles != nil {
+			err = profiles.VisitElements(updateManifests)
+			if err != nil {
+				return errors.Wrapf(err, "failed to update profile manifests")
+			}
+		}
+	}
+	if !modified {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err = (&kio.ByteWriter{Writer: &buf}).Write(nodes)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal skaffold file %s", path)
+	}
+	err = ioutil.WriteFile(path, buf.Bytes(), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
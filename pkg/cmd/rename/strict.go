@@ -0,0 +1,29 @@
+package rename
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// checkStrictYAML parses every document in the file rejecting duplicate keys, unknown anchors and tab indentation
+func checkStrictYAML(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+	decoder := yamlv3.NewDecoder(bytes.NewReader(data))
+	for {
+		var value interface{}
+		err = decoder.Decode(&value)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to strictly parse file %s", path)
+		}
+	}
+}
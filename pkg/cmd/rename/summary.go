@@ -0,0 +1,99 @@
+package rename
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/pkg/errors"
+)
+
+// Summary a machine readable summary of the results of the command
+type Summary struct {
+	Scanned  int              `json:"scanned"`
+	Renamed  int              `json:"renamed"`
+	Skipped  int              `json:"skipped"`
+	Errors   int              `json:"errors"`
+	Renames  []SummaryRename  `json:"renames,omitempty"`
+	Failures []SummaryFailure `json:"failures,omitempty"`
+}
+
+// SummaryRename a file which was renamed
+type SummaryRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// SummaryFailure a file which could not be renamed
+type SummaryFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// CreateSummary creates the summary of the results
+func (o *Options) CreateSummary() *Summary {
+	s := &Summary{}
+	for _, r := range o.Results {
+		s.Scanned++
+		switch r.Action {
+		case ActionRenamed:
+			s.Renamed++
+			s.Renames = append(s.Renames, SummaryRename{
+				From: r.Path,
+				To:   r.Canonical,
+				Kind: r.Kind,
+				Name: r.Name,
+			})
+		case ActionError:
+			s.Errors++
+			failure := SummaryFailure{Path: r.Path}
+			if r.Error != nil {
+				failure.Error = r.Error.Error()
+			}
+			s.Failures = append(s.Failures, failure)
+		default:
+			s.Skipped++
+		}
+	}
+	return s
+}
+
+func (o *Options) writeSummary() {
+	s := o.CreateSummary()
+	fmt.Fprintf(o.Out, "%d renamed, %d skipped, %d errors\n", s.Renamed, s.Skipped, s.Errors)
+}
+
+func (o *Options) writeSummaryYAML() error {
+	err := yamls.SaveFile(o.CreateSummary(), o.SummaryYAML)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save summary file %s", o.SummaryYAML)
+	}
+	return nil
+}
+
+// CreateInverseMap returns a map of the canonical file names to the original file names relative to the directory
+func (o *Options) CreateInverseMap() map[string]string {
+	m := map[string]string{}
+	for _, r := range o.Results {
+		if r.Action == ActionRenamed {
+			m[o.relativePath(r.Canonical)] = o.relativePath(r.Path)
+		}
+	}
+	return m
+}
+
+func (o *Options) writeInverseMap() error {
+	data, err := json.MarshalIndent(o.CreateInverseMap(), "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal inverse map")
+	}
+	err = ioutil.WriteFile(o.InverseMap, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", o.InverseMap)
+	}
+	return nil
+}
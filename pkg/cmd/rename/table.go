@@ -0,0 +1,87 @@
+package rename
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+)
+
+// minPathColumnWidth the minimum width a path column is truncated to
+const minPathColumnWidth = 12
+
+// writeTable writes a table of the results sorted by action then by original path
+func (o *Options) writeTable() {
+	results := append([]*FileResult{}, o.Results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Action != results[j].Action {
+			return results[i].Action < results[j].Action
+		}
+		return results[i].Path < results[j].Path
+	})
+
+	rows := [][]string{{"ORIGINAL", "CANONICAL", "KIND", "NAME", "ACTION"}}
+	for _, r := range results {
+		canonical := ""
+		if r.Canonical != "" {
+			canonical = o.relativePath(r.Canonical)
+		}
+		rows = append(rows, []string{o.relativePath(r.Path), canonical, r.Kind, r.Name, r.Action})
+	}
+
+	pathWidth := tablePathWidth(rows, terminalWidth())
+	t := table.CreateTable(o.Out)
+	for _, row := range rows {
+		if pathWidth > 0 {
+			row[0] = truncatePath(row[0], pathWidth)
+			row[1] = truncatePath(row[1], pathWidth)
+		}
+		t.AddRow(row...)
+	}
+	t.Render()
+}
+
+// terminalWidth returns the width of the terminal from the COLUMNS environment variable or 0 if it is not known
+func terminalWidth() int {
+	columns, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || columns <= 0 {
+		return 0
+	}
+	return columns
+}
+
+// tablePathWidth returns the maximum width of the path columns so that the rows fit in the given width
+// or 0 if the paths do not need to be truncated
+func tablePathWidth(rows [][]string, width int) int {
+	if width <= 0 {
+		return 0
+	}
+	widths := make([]int, 5)
+	for _, row := range rows {
+		for i, col := range row {
+			l := utf8.RuneCountInString(col)
+			if l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	if widths[0]+widths[1]+widths[2]+widths[3]+widths[4]+len(widths)-1 <= width {
+		return 0
+	}
+	pathWidth := (width - widths[2] - widths[3] - widths[4] - len(widths) + 1) / 2
+	if pathWidth < minPathColumnWidth {
+		pathWidth = minPathColumnWidth
+	}
+	return pathWidth
+}
+
+// truncatePath truncates the start of the path so that it is no longer than the given width
+func truncatePath(path string, width int) string {
+	runes := []rune(path)
+	if len(runes) <= width {
+		return path
+	}
+	return "..." + string(runes[len(runes)-width+3:])
+}
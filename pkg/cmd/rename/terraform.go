@@ -0,0 +1,106 @@
+package rename
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const terraformModulePath = "${path.module}/"
+
+// terraformFileRefRegex matches the path of the file() and templatefile() function calls in Terraform files
+var terraformFileRefRegex = regexp.MustCompile(`\b((?:file|templatefile)\(\s*")([^"]+)(")`)
+
+// updateTerraformRefs updates the file() and templatefile() references to renamed files in the Terraform files
+func (o *Options) updateTerraformRefs() error {
+	renames := map[string]string{}
+	for canonical, original := range o.CreateInverseMap() {
+		renames[filepath.ToSlash(original)] = filepath.ToSlash(canonical)
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	dir := o.TerraformDir
+	if dir == "" {
+		dir = o.Dir
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".terraform" || info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".tf" {
+			return nil
+		}
+		return o.updateTerraformFile(path, renames)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update Terraform files in dir %s", dir)
+	}
+	return nil
+}
+
+func (o *Options) updateTerraformFile(path string, renames map[string]string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", path)
+	}
+	text := string(data)
+	dir := filepath.Dir(path)
+	modified := false
+	result := terraformFileRefRegex.ReplaceAllStringFunc(text, func(match string) string {
+		groups := terraformFileRefRegex.FindStringSubmatch(match)
+		ref := groups[2]
+		prefix := ""
+		if strings.HasPrefix(ref, terraformModulePath) {
+			prefix = terraformModulePath
+		} else if strings.Contains(ref, "${") {
+			return match
+		}
+		rel := strings.TrimPrefix(ref, prefix)
+		if filepath.IsAbs(rel) {
+			return match
+		}
+		original, err := filepath.Rel(o.Dir, filepath.Join(dir, rel))
+		if err != nil {
+			return match
+		}
+		canonical := renames[filepath.ToSlash(original)]
+		if canonical == "" {
+			return match
+		}
+		newRel, err := filepath.Rel(dir, filepath.Join(o.Dir, canonical))
+		if err != nil {
+			return match
+		}
+		newRel = filepath.ToSlash(newRel)
+		if strings.HasPrefix(rel, "./") && !strings.HasPrefix(newRel, ".") {
+			newRel = "./" + newRel
+		}
+		newRef := prefix + newRel
+		log.Logger().Infof("updated Terraform file %s reference %s => %s", o.relativePath(path), ref, newRef)
+		o.references = append(o.references, reference{Kind: "Terraform", Path: o.relativePath(path), File: canonical})
+		modified = true
+		return groups[1] + newRef + groups[3]
+	})
+	if !modified {
+		return nil
+	}
+	err = ioutil.WriteFile(path, []byte(result), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
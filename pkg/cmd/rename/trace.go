@@ -0,0 +1,42 @@
+package rename
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// TraceEntry the trace of processing a single file
+type TraceEntry struct {
+	Path            string  `json:"path"`
+	Kind            string  `json:"kind,omitempty"`
+	Name            string  `json:"name,omitempty"`
+	Canonical       string  `json:"canonical,omitempty"`
+	Action          string  `json:"action"`
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+func (o *Options) writeTrace(r *FileResult) error {
+	entry := &TraceEntry{
+		Path:            r.Path,
+		Kind:            r.Kind,
+		Name:            r.Name,
+		Canonical:       r.Canonical,
+		Action:          r.Action,
+		DurationSeconds: r.Duration.Seconds(),
+	}
+	if r.Error != nil {
+		entry.Error = r.Error.Error()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal trace entry for %s", r.Path)
+	}
+	_, err = fmt.Fprintln(o.traceOut, string(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to write trace entry for %s", r.Path)
+	}
+	return nil
+}
@@ -10,6 +10,9 @@ import (
 
 // DefaultValues defaults values from the given config, group and repository if they are missing
 func DefaultValues(config *v1alpha1.SourceConfig, group *v1alpha1.RepositoryGroup, repo *v1alpha1.Repository) error {
+	if config.Spec.Defaults != nil {
+		defaultGroupValues(config.Spec.Defaults, group)
+	}
 	if group.Provider == "" {
 		group.Provider = "https://github.com"
 	}
@@ -43,16 +46,65 @@ func DefaultValues(config *v1alpha1.SourceConfig, group *v1alpha1.RepositoryGrou
 		repo.Jenkins = group.Jenkins
 	}
 	if repo.Jenkins != nil && group.Jenkins != nil {
-		if repo.Jenkins.Server == "" {
-			repo.Jenkins.Server = group.Jenkins.Server
-		}
-		if repo.Jenkins.XmlTemplate == "" {
-			repo.Jenkins.XmlTemplate = group.Jenkins.XmlTemplate
-		}
+		defaultJenkinsValues(group.Jenkins, repo.Jenkins)
 	}
 	return nil
 }
 
+// defaultGroupValues defaults the group values from the spec level defaults if they are missing
+func defaultGroupValues(defaults *v1alpha1.RepositoryDefaults, group *v1alpha1.RepositoryGroup) {
+	if group.Provider == "" {
+		group.Provider = defaults.Provider
+	}
+	if group.ProviderKind == "" {
+		group.ProviderKind = defaults.ProviderKind
+	}
+	if group.ProviderName == "" {
+		group.ProviderName = defaults.ProviderName
+	}
+
+	if defaults.Jenkins == nil {
+		return
+	}
+	if group.Jenkins == nil {
+		jc := *defaults.Jenkins
+		group.Jenkins = &jc
+		return
+	}
+	defaultJenkinsValues(defaults.Jenkins, group.Jenkins)
+}
+
+// defaultJenkinsValues defaults the Jenkins configuration from the Jenkins configuration of the parent if they are missing
+func defaultJenkinsValues(parent, jenkins *v1alpha1.JenkinsConfig) {
+	if jenkins.Server == "" {
+		jenkins.Server = parent.Server
+	}
+	if jenkins.XmlTemplate == "" {
+		jenkins.XmlTemplate = parent.XmlTemplate
+	}
+	if len(jenkins.SharedLibraries) == 0 {
+		jenkins.SharedLibraries = parent.SharedLibraries
+	}
+	if len(jenkins.GlobalLibraries) == 0 {
+		jenkins.GlobalLibraries = parent.GlobalLibraries
+	}
+	if jenkins.SlackNotification == nil {
+		jenkins.SlackNotification = parent.SlackNotification
+	}
+	if jenkins.SonarQube == nil {
+		jenkins.SonarQube = parent.SonarQube
+	}
+	if jenkins.NexusIQ == nil {
+		jenkins.NexusIQ = parent.NexusIQ
+	}
+	if jenkins.KEDA == nil {
+		jenkins.KEDA = parent.KEDA
+	}
+	if jenkins.BuildTimeout == nil {
+		jenkins.BuildTimeout = parent.BuildTimeout
+	}
+}
+
 // GetOrCreateGroup get or create the group for the given name
 func GetOrCreateGroup(config *v1alpha1.SourceConfig, gitKind string, gitServerURL string, owner string) *v1alpha1.RepositoryGroup {
 	for i := range config.Spec.Groups {
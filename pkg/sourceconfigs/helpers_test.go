@@ -0,0 +1,81 @@
+package sourceconfigs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x/jx-gitops/pkg/sourceconfigs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDefaultValuesPrecedence(t *testing.T) {
+	specLibraries := []v1alpha1.SharedLibraryConfig{{Name: "spec-lib", URL: "https://github.com/myorg/spec-lib.git"}}
+	groupLibraries := []v1alpha1.SharedLibraryConfig{{Name: "group-lib", URL: "https://github.com/myorg/group-lib.git"}}
+	specTimeout := &metav1.Duration{Duration: 30 * time.Minute}
+	repoTimeout := &metav1.Duration{Duration: 90 * time.Minute}
+
+	config := &v1alpha1.SourceConfig{
+		Spec: v1alpha1.SourceConfigSpec{
+			Defaults: &v1alpha1.RepositoryDefaults{
+				Provider: "https://gitlab.example.com",
+				Jenkins: &v1alpha1.JenkinsConfig{
+					Server:            "spec-jenkins",
+					XmlTemplate:       "spec.xml.gotmpl",
+					SharedLibraries:   specLibraries,
+					GlobalLibraries:   specLibraries,
+					SlackNotification: &v1alpha1.SlackConfig{Channel: "#spec"},
+					SonarQube:         &v1alpha1.SonarConfig{ServerURL: "https://sonar.spec"},
+					NexusIQ:           &v1alpha1.NexusConfig{Stage: "spec"},
+					KEDA:              &v1alpha1.KEDAConfig{QueueLabel: "spec"},
+					BuildTimeout:      specTimeout,
+				},
+			},
+		},
+	}
+	group := &v1alpha1.RepositoryGroup{
+		Owner: "myorg",
+		Jenkins: &v1alpha1.JenkinsConfig{
+			Server:          "group-jenkins",
+			SharedLibraries: groupLibraries,
+			SonarQube:       &v1alpha1.SonarConfig{ServerURL: "https://sonar.group"},
+		},
+	}
+	repo := &v1alpha1.Repository{
+		Name: "myapp",
+		Jenkins: &v1alpha1.JenkinsConfig{
+			XmlTemplate:  "repo.xml.gotmpl",
+			NexusIQ:      &v1alpha1.NexusConfig{Stage: "repo"},
+			BuildTimeout: repoTimeout,
+		},
+	}
+
+	err := sourceconfigs.DefaultValues(config, group, repo)
+	require.NoError(t, err, "failed to default values")
+
+	assert.Equal(t, "https://gitlab.example.com", group.Provider, "group provider from the spec")
+
+	// the group values override the spec values
+	assert.Equal(t, "group-jenkins", group.Jenkins.Server, "group server")
+	assert.Equal(t, groupLibraries, group.Jenkins.SharedLibraries, "group shared libraries")
+	assert.Equal(t, "https://sonar.group", group.Jenkins.SonarQube.ServerURL, "group SonarQube")
+	assert.Equal(t, "spec.xml.gotmpl", group.Jenkins.XmlTemplate, "group XML template from the spec")
+	assert.Equal(t, specLibraries, group.Jenkins.GlobalLibraries, "group global libraries from the spec")
+	assert.Equal(t, "#spec", group.Jenkins.SlackNotification.Channel, "group slack notification from the spec")
+	assert.Equal(t, "spec", group.Jenkins.NexusIQ.Stage, "group NexusIQ from the spec")
+	assert.Equal(t, "spec", group.Jenkins.KEDA.QueueLabel, "group KEDA from the spec")
+	assert.Equal(t, specTimeout, group.Jenkins.BuildTimeout, "group build timeout from the spec")
+
+	// the repository values override the group values which override the spec values
+	assert.Equal(t, "repo.xml.gotmpl", repo.Jenkins.XmlTemplate, "repository XML template")
+	assert.Equal(t, "repo", repo.Jenkins.NexusIQ.Stage, "repository NexusIQ")
+	assert.Equal(t, repoTimeout, repo.Jenkins.BuildTimeout, "repository build timeout")
+	assert.Equal(t, "group-jenkins", repo.Jenkins.Server, "repository server from the group")
+	assert.Equal(t, groupLibraries, repo.Jenkins.SharedLibraries, "repository shared libraries from the group")
+	assert.Equal(t, "https://sonar.group", repo.Jenkins.SonarQube.ServerURL, "repository SonarQube from the group")
+	assert.Equal(t, specLibraries, repo.Jenkins.GlobalLibraries, "repository global libraries from the spec")
+	assert.Equal(t, "#spec", repo.Jenkins.SlackNotification.Channel, "repository slack notification from the spec")
+	assert.Equal(t, "spec", repo.Jenkins.KEDA.QueueLabel, "repository KEDA from the spec")
+}